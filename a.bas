@@ -0,0 +1,12 @@
+10 FOR I=1 TO 10 STEP 2
+20 PRINT I;
+30 NEXT I
+40 PRINT
+50 PRINT "I=";I
+60 FOR B=10.0 TO 1.0 STEP -0.5:PRINT B;:NEXT
+70 PRINT
+80 GOSUB 200
+90 PRINT "back"
+100 END
+200 PRINT "in 200":GOSUB 300:PRINT "after 300":RETURN
+300 PRINT "in 300":RETURN
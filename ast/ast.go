@@ -26,15 +26,16 @@ SOFTWARE.
 
 import (
 	"bytes"
-	"strconv"
 	"strings"
 
 	"github.com/ysh86/b2c/token"
+	"github.com/ysh86/b2c/types"
 )
 
 // The base Node interface
 type Node interface {
 	TokenLiteral() string
+	Pos() token.Position
 	String() string
 }
 
@@ -62,37 +63,80 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	} else {
+		return token.Position{}
+	}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
-	for _, s := range p.Statements {
+	writeStatements(&out, p.Statements)
+
+	return out.String()
+}
+
+// writeStatements writes stmts as a BASIC listing: a line number starts a
+// new line, the other statements are separated by ':'.
+func writeStatements(out *bytes.Buffer, stmts []Statement) {
+	sep := ""
+	for _, s := range stmts {
+		if _, ok := s.(*LineNoStatement); ok {
+			if sep != "" {
+				out.WriteString("\n")
+			}
+			out.WriteString(s.String())
+			sep = " "
+			continue
+		}
+
+		out.WriteString(sep)
 		out.WriteString(s.String())
-		out.WriteString("\n")
+		sep = ":"
 	}
+}
 
-	return out.String()
+// writeBody writes the statements of a loop or a block IF after the
+// statement that starts it, sep before the first one. A line number starts a new line.
+// It returns the separator before the statement that ends the body: a
+// colon, or a space after a line number.
+func writeBody(out *bytes.Buffer, sep string, stmts []Statement) string {
+	for _, s := range stmts {
+		if _, ok := s.(*LineNoStatement); ok {
+			out.WriteString("\n")
+			out.WriteString(s.String())
+			sep = " "
+			continue
+		}
+
+		out.WriteString(sep)
+		out.WriteString(s.String())
+		sep = ":"
+	}
+	return sep
+}
+
+// writeTarget writes a GOTO/GOSUB target: a line number or a '*' label.
+func writeTarget(out *bytes.Buffer, name *Identifier) {
+	if !name.IsLineNo() {
+		out.WriteString("*")
+	}
+	out.WriteString(name.String())
 }
 
 // Statements
 type LineNoStatement struct {
 	Token token.Token // the token.LINENO token
 	Name  *Identifier // copy from Token.Literal
-	Data  *DataStatement
 }
 
 func (lns *LineNoStatement) statementNode()       {}
 func (lns *LineNoStatement) TokenLiteral() string { return lns.Token.Literal }
-func (lns *LineNoStatement) String() string {
-	var out bytes.Buffer
-
-	if lns.Data == nil {
-		out.WriteString("_" + lns.Name.String() + ":;")
-	} else {
-		out.WriteString(lns.Data.String())
-	}
-
-	return out.String()
-}
+func (lns *LineNoStatement) Pos() token.Position  { return lns.Token.Pos }
+func (lns *LineNoStatement) String() string       { return lns.Name.String() }
 
 type LabelStatement struct {
 	Token token.Token // the '*' token
@@ -101,15 +145,8 @@ type LabelStatement struct {
 
 func (ls *LabelStatement) statementNode()       {}
 func (ls *LabelStatement) TokenLiteral() string { return ls.Token.Literal }
-func (ls *LabelStatement) String() string {
-	var out bytes.Buffer
-
-	out.WriteString("\n")
-	out.WriteString("// -----------------------------------\n")
-	out.WriteString(ls.Name.String() + ":;")
-
-	return out.String()
-}
+func (ls *LabelStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LabelStatement) String() string       { return "*" + ls.Name.String() }
 
 type DimStatement struct {
 	Token  token.Token // the token.DIM token
@@ -119,71 +156,124 @@ type DimStatement struct {
 
 func (ds *DimStatement) statementNode()       {}
 func (ds *DimStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DimStatement) Pos() token.Position  { return ds.Token.Pos }
 func (ds *DimStatement) String() string {
 	var out bytes.Buffer
 
-	l := len(ds.Names)
-
-	if l > 0 {
-		out.WriteString("int ") // TODO: char がいる
-		out.WriteString(ds.Names[0].String())
-		ll := len(ds.Values[0])
-		for j := 0; j < ll; j++ { // TODO: x,y が逆かも
-			out.WriteString("[")
-			out.WriteString(ds.Values[0][j].String())
-			out.WriteString("]")
+	out.WriteString("DIM ")
+	for i, n := range ds.Names {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		dims := []string{}
+		for _, v := range ds.Values[i] {
+			dims = append(dims, v.String())
 		}
+		out.WriteString(n.String())
+		out.WriteString("(")
+		out.WriteString(strings.Join(dims, ", "))
+		out.WriteString(")")
 	}
 
-	for i := 1; i < l; i++ {
-		out.WriteString(", ")
-		out.WriteString(ds.Names[i].String())
-		ll := len(ds.Values[i])
-		for j := 0; j < ll; j++ { // TODO: x,y が逆かも
-			out.WriteString("[")
-			out.WriteString(ds.Values[i][j].String())
-			out.WriteString("]")
+	return out.String()
+}
+
+// DefFnStatement defines a user function: DEF FNA(X)=X*X+1.
+type DefFnStatement struct {
+	Token      token.Token // the token.DEF token
+	Name       *Identifier // FNA, or FNA$ for a string function
+	Parameters []*Identifier
+	Value      Expression
+}
+
+func (ds *DefFnStatement) statementNode()       {}
+func (ds *DefFnStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DefFnStatement) Pos() token.Position  { return ds.Token.Pos }
+func (ds *DefFnStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("DEF ")
+	out.WriteString(ds.Name.String())
+	if len(ds.Parameters) > 0 {
+		params := []string{}
+		for _, p := range ds.Parameters {
+			params = append(params, p.String())
 		}
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(")")
 	}
-
-	out.WriteString(";")
+	out.WriteString(" = ")
+	out.WriteString(ds.Value.String())
 
 	return out.String()
 }
 
 type IfStatement struct {
-	Token       token.Token // The 'if' token
+	Token       token.Token // The 'if' token, or the 'ELSEIF' token of a block IF
 	Condition   Expression
 	Consequence []Statement
-	Alternative []Statement
+	Alternative []Statement // an ELSEIF is an IfStatement alone in Alternative
+	Block       bool        // the block form ended by END IF
 }
 
 func (is *IfStatement) statementNode()       {}
 func (is *IfStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *IfStatement) Pos() token.Position  { return is.Token.Pos }
 func (is *IfStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if (")
+	out.WriteString(is.Token.Literal + " ")
 	out.WriteString(is.Condition.String())
-	out.WriteString(") {\n")
-	for _, s := range is.Consequence {
-		out.WriteString("    ")
-		out.WriteString(s.String())
-		out.WriteString("\n")
+	if is.Block {
+		out.WriteString(" THEN")
+		is.writeBlock(&out)
+		return out.String()
 	}
+
+	out.WriteString(" THEN ")
+	writeStatements(&out, is.Consequence)
 	if len(is.Alternative) != 0 {
-		out.WriteString("} else {\n")
-		for _, s := range is.Alternative {
-			out.WriteString("    ")
-			out.WriteString(s.String())
-			out.WriteString("\n")
-		}
+		out.WriteString(" ELSE ")
+		writeStatements(&out, is.Alternative)
 	}
-	out.WriteString("}")
 
 	return out.String()
 }
 
+// writeBlock writes the lines of a block IF after THEN. ELSEIF, ELSE and
+// END IF start their lines, after the line numbers if any.
+func (is *IfStatement) writeBlock(out *bytes.Buffer) {
+	line := func(sep string) string {
+		if sep == ":" {
+			return "\n"
+		}
+		return sep
+	}
+
+	sep := line(writeBody(out, "\n", is.Consequence))
+	if elseIf := is.ElseIf(); elseIf != nil {
+		out.WriteString(sep + elseIf.String()) // it ends with END IF
+		return
+	}
+	if len(is.Alternative) != 0 {
+		out.WriteString(sep + "ELSE")
+		sep = line(writeBody(out, "\n", is.Alternative))
+	}
+	out.WriteString(sep + "END IF")
+}
+
+// ElseIf returns the ELSEIF part of a block IF, or nil if there is none.
+func (is *IfStatement) ElseIf() *IfStatement {
+	if !is.Block || len(is.Alternative) != 1 {
+		return nil
+	}
+	if s, ok := is.Alternative[0].(*IfStatement); ok && s.Token.Type == token.ELSEIF {
+		return s
+	}
+	return nil
+}
+
 type OnStatement struct {
 	Token       token.Token // the token.ON token
 	Value       Expression
@@ -193,49 +283,20 @@ type OnStatement struct {
 
 func (os *OnStatement) statementNode()       {}
 func (os *OnStatement) TokenLiteral() string { return os.Token.Literal }
+func (os *OnStatement) Pos() token.Position  { return os.Token.Pos }
 func (os *OnStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("switch (")
+	out.WriteString("ON ")
 	out.WriteString(os.Value.String())
-	out.WriteString(") {\n")
-
+	out.WriteString(" " + string(os.Instruction.Type) + " ")
 	for i, n := range os.Names {
-		out.WriteString("case ")
-		out.WriteString(strconv.Itoa(i + 1)) // 1 origin
-		out.WriteString(":\n")
-
-		var c byte
-		if len(n.String()) > 0 {
-			c = n.String()[0]
+		if i > 0 {
+			out.WriteString(", ")
 		}
-
-		if os.Instruction.Type == token.GOTO {
-			out.WriteString("    goto ")
-			if '0' <= c && c <= '9' {
-				out.WriteString("_")
-			}
-			out.WriteString(n.String())
-			out.WriteString(";\n")
-		} else {
-			out.WriteString("    if (setjmp(env) == 0) {\n")
-			out.WriteString("        goto ")
-			if '0' <= c && c <= '9' {
-				out.WriteString("_")
-			}
-			out.WriteString(n.String())
-			out.WriteString(";\n")
-			out.WriteString("    }\n")
-			out.WriteString("    // return from longjmp()\n")
-		}
-		out.WriteString("    break;\n")
+		writeTarget(&out, n)
 	}
 
-	out.WriteString("default:\n")
-	out.WriteString("    // nothing to do\n")
-	out.WriteString("    break;\n")
-	out.WriteString("}")
-
 	return out.String()
 }
 
@@ -246,20 +307,12 @@ type GotoStatement struct {
 
 func (gs *GotoStatement) statementNode()       {}
 func (gs *GotoStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GotoStatement) Pos() token.Position  { return gs.Token.Pos }
 func (gs *GotoStatement) String() string {
 	var out bytes.Buffer
 
-	var c byte
-	if len(gs.Name.String()) > 0 {
-		c = gs.Name.String()[0]
-	}
-
-	out.WriteString("goto ") // TODO: 飛び先に RETURN があると死ぬ
-	if '0' <= c && c <= '9' {
-		out.WriteString("_")
-	}
-	out.WriteString(gs.Name.String())
-	out.WriteString(";")
+	out.WriteString("GOTO ")
+	writeTarget(&out, gs.Name)
 
 	return out.String()
 }
@@ -271,23 +324,12 @@ type GosubStatement struct {
 
 func (gss *GosubStatement) statementNode()       {}
 func (gss *GosubStatement) TokenLiteral() string { return gss.Token.Literal }
+func (gss *GosubStatement) Pos() token.Position  { return gss.Token.Pos }
 func (gss *GosubStatement) String() string {
 	var out bytes.Buffer
 
-	var c byte
-	if len(gss.Name.String()) > 0 {
-		c = gss.Name.String()[0]
-	}
-
-	out.WriteString("if (setjmp(env) == 0) {\n")
-	out.WriteString("    goto ")
-	if '0' <= c && c <= '9' {
-		out.WriteString("_")
-	}
-	out.WriteString(gss.Name.String())
-	out.WriteString(";\n")
-	out.WriteString("}\n")
-	out.WriteString("// return from longjmp()")
+	out.WriteString("GOSUB ")
+	writeTarget(&out, gss.Name)
 
 	return out.String()
 }
@@ -298,70 +340,314 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
-func (rs *ReturnStatement) String() string {
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) String() string       { return "RETURN" }
+
+// OnErrorStatement is ON ERROR GOTO.
+type OnErrorStatement struct {
+	Token token.Token // the token.ON token
+	Name  *Identifier // the handler, nil for ON ERROR GOTO 0
+}
+
+func (oe *OnErrorStatement) statementNode()       {}
+func (oe *OnErrorStatement) TokenLiteral() string { return oe.Token.Literal }
+func (oe *OnErrorStatement) Pos() token.Position  { return oe.Token.Pos }
+func (oe *OnErrorStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("longjmp(env, 1);\n")
-	out.WriteString("// -----------------------------------")
+	out.WriteString("ON ERROR GOTO ")
+	if oe.Name == nil {
+		out.WriteString("0")
+	} else {
+		writeTarget(&out, oe.Name)
+	}
 
 	return out.String()
 }
 
+type ResumeStatement struct {
+	Token token.Token // the token.RESUME token
+	Next  bool        // RESUME NEXT
+	Name  *Identifier // RESUME 100, nil to retry the statement in error
+}
+
+func (rs *ResumeStatement) statementNode()       {}
+func (rs *ResumeStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ResumeStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ResumeStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("RESUME")
+	switch {
+	case rs.Next:
+		out.WriteString(" NEXT")
+	case rs.Name != nil:
+		out.WriteString(" ")
+		writeTarget(&out, rs.Name)
+	}
+
+	return out.String()
+}
+
+// ErrorStatement raises the error Value: ERROR 11.
+type ErrorStatement struct {
+	Token token.Token // the token.ERROR token
+	Value Expression
+}
+
+func (es *ErrorStatement) statementNode()       {}
+func (es *ErrorStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ErrorStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *ErrorStatement) String() string       { return "ERROR " + es.Value.String() }
+
 type ForStatement struct {
 	Token      token.Token // the token.FOR token
 	Name       *Identifier
 	Begin      Expression
 	End        Expression
-	Step       Expression
+	Step       Expression // nil if omitted, which is STEP 1
 	Statements []Statement
+	Next       *Identifier    // the variable after NEXT, nil for a bare NEXT
+	IfNext     *NextStatement // the NEXT in the last statement, an IF, that ends the loop
 }
 
 func (fs *ForStatement) statementNode()       {}
 func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
 func (fs *ForStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("for (")
-	out.WriteString("int ") // TODO: float
+	out.WriteString("FOR ")
 	out.WriteString(fs.Name.String())
 	out.WriteString(" = ")
 	out.WriteString(fs.Begin.String())
-	out.WriteString("; ")
-	out.WriteString(fs.Name.String())
-	out.WriteString(" != ")
+	out.WriteString(" TO ")
 	out.WriteString(fs.End.String())
-	out.WriteString("; ")
-	out.WriteString(fs.Name.String())
-	out.WriteString(" += ")
-	out.WriteString(fs.Step.String())
-	out.WriteString(") {\n")
-	for _, s := range fs.Statements {
-		out.WriteString("    ")
-		out.WriteString(s.String())
-		out.WriteString("\n")
+	if fs.Step != nil {
+		out.WriteString(" STEP ")
+		out.WriteString(fs.Step.String())
+	}
+	sep := writeBody(&out, ":", fs.Statements)
+	if fs.IfNext != nil {
+		return out.String()
+	}
+	out.WriteString(sep + "NEXT")
+	if fs.Next != nil {
+		out.WriteString(" " + fs.Next.String())
+	}
+
+	return out.String()
+}
+
+// NextStatement is a NEXT in a branch of an IF that ends the FOR around
+// the IF: FOR I=1 TO N:IF A(I)<>K THEN NEXT. The loop is left if the
+// branch is not taken.
+type NextStatement struct {
+	Token token.Token // the token.NEXT token
+	Name  *Identifier // nil for a bare NEXT
+}
+
+func (ns *NextStatement) statementNode()       {}
+func (ns *NextStatement) TokenLiteral() string { return ns.Token.Literal }
+func (ns *NextStatement) Pos() token.Position  { return ns.Token.Pos }
+func (ns *NextStatement) String() string {
+	if ns.Name == nil {
+		return "NEXT"
 	}
-	out.WriteString("}")
+	return "NEXT " + ns.Name.String()
+}
+
+type WhileStatement struct {
+	Token      token.Token // the token.WHILE token
+	Condition  Expression
+	Statements []Statement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("WHILE ")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(writeBody(&out, ":", ws.Statements) + "WEND")
 
 	return out.String()
 }
 
+// DoStatement is DO...LOOP. The condition is tested before each iteration
+// in DO WHILE and DO UNTIL, and after it in LOOP WHILE and LOOP UNTIL.
+type DoStatement struct {
+	Token      token.Token // the token.DO token
+	Condition  Expression  // nil for an endless loop
+	Until      bool        // loop until Condition is true
+	Post       bool        // Condition follows LOOP
+	Statements []Statement
+}
+
+func (ds *DoStatement) statementNode()       {}
+func (ds *DoStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DoStatement) Pos() token.Position  { return ds.Token.Pos }
+func (ds *DoStatement) String() string {
+	var out bytes.Buffer
+
+	cond := ""
+	if ds.Condition != nil {
+		if ds.Until {
+			cond = " UNTIL " + ds.Condition.String()
+		} else {
+			cond = " WHILE " + ds.Condition.String()
+		}
+	}
+
+	out.WriteString("DO")
+	if !ds.Post {
+		out.WriteString(cond)
+	}
+	out.WriteString(writeBody(&out, ":", ds.Statements) + "LOOP")
+	if ds.Post {
+		out.WriteString(cond)
+	}
+
+	return out.String()
+}
+
+type ExitStatement struct {
+	Token token.Token // the token.EXIT token
+	Loop  token.Token // the token.DO token
+}
+
+func (es *ExitStatement) statementNode()       {}
+func (es *ExitStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExitStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *ExitStatement) String() string       { return "EXIT " + es.Loop.Literal }
+
 type DataStatement struct {
 	Token token.Token // the token.DATA token
-	Name  *Identifier
-	Value string
+	Value string      // the text after DATA
+	Items []DataItem
+}
+
+// DataItem is a constant of DATA. It is a string or a number depending on
+// the variable READ into.
+type DataItem struct {
+	Value  string // the text without the quotes and the spaces around it
+	Quoted bool
 }
 
 func (das *DataStatement) statementNode()       {}
 func (das *DataStatement) TokenLiteral() string { return das.Token.Literal }
-func (das *DataStatement) String() string {
+func (das *DataStatement) Pos() token.Position  { return das.Token.Pos }
+func (das *DataStatement) String() string       { return "DATA " + das.Value }
+
+type ReadStatement struct {
+	Token token.Token // the token.READ token
+	Names []*Identifier
+}
+
+func (rs *ReadStatement) statementNode()       {}
+func (rs *ReadStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReadStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *ReadStatement) String() string {
+	names := []string{}
+	for _, n := range rs.Names {
+		names = append(names, n.String())
+	}
+	return "READ " + strings.Join(names, ", ")
+}
+
+type RestoreStatement struct {
+	Token token.Token // the token.RESTORE token
+	Name  *Identifier // the line number or the label, nil for the first DATA
+}
+
+func (rs *RestoreStatement) statementNode()       {}
+func (rs *RestoreStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RestoreStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *RestoreStatement) String() string {
 	var out bytes.Buffer
 
-	// TODO: すでに "0123456789" の時がある
-	out.WriteString("char *_" + das.Name.String() + " = \"" + das.Value + "\";")
+	out.WriteString("RESTORE")
+	if rs.Name != nil {
+		out.WriteString(" ")
+		writeTarget(&out, rs.Name)
+	}
 
 	return out.String()
 }
 
+type RandomizeStatement struct {
+	Token token.Token // the token.RANDOMIZE token
+	Value Expression  // the seed, nil to ask for it
+}
+
+func (rs *RandomizeStatement) statementNode()       {}
+func (rs *RandomizeStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RandomizeStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *RandomizeStatement) String() string {
+	if rs.Value == nil {
+		return "RANDOMIZE"
+	}
+	return "RANDOMIZE " + rs.Value.String()
+}
+
+type EndStatement struct {
+	Token token.Token // the token.END token
+}
+
+func (es *EndStatement) statementNode()       {}
+func (es *EndStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EndStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *EndStatement) String() string       { return "END" }
+
+type StopStatement struct {
+	Token token.Token // the token.STOP token
+}
+
+func (ss *StopStatement) statementNode()       {}
+func (ss *StopStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StopStatement) Pos() token.Position  { return ss.Token.Pos }
+func (ss *StopStatement) String() string       { return "STOP" }
+
+type ClearStatement struct {
+	Token token.Token // the token.CLEAR token
+}
+
+func (cs *ClearStatement) statementNode()       {}
+func (cs *ClearStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ClearStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ClearStatement) String() string       { return "CLEAR" }
+
+type SwapStatement struct {
+	Token token.Token // the token.SWAP token
+	Left  *Identifier
+	Right *Identifier
+}
+
+func (ss *SwapStatement) statementNode()       {}
+func (ss *SwapStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SwapStatement) Pos() token.Position  { return ss.Token.Pos }
+func (ss *SwapStatement) String() string {
+	return "SWAP " + ss.Left.String() + ", " + ss.Right.String()
+}
+
+type EraseStatement struct {
+	Token token.Token // the token.ERASE token
+	Names []*Identifier
+}
+
+func (es *EraseStatement) statementNode()       {}
+func (es *EraseStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EraseStatement) Pos() token.Position  { return es.Token.Pos }
+func (es *EraseStatement) String() string {
+	names := []string{}
+	for _, n := range es.Names {
+		names = append(names, n.String())
+	}
+	return "ERASE " + strings.Join(names, ", ")
+}
+
 type LetStatement struct {
 	Token token.Token // the token.LET token
 	Name  *Identifier
@@ -370,6 +656,7 @@ type LetStatement struct {
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
@@ -380,8 +667,6 @@ func (ls *LetStatement) String() string {
 		out.WriteString(ls.Value.String())
 	}
 
-	out.WriteString(";")
-
 	return out.String()
 }
 
@@ -392,17 +677,271 @@ type CallStatement struct {
 
 func (cs *CallStatement) statementNode()       {}
 func (cs *CallStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *CallStatement) Pos() token.Position  { return cs.Token.Pos }
 func (cs *CallStatement) String() string {
+	if cs.Expression == nil {
+		return ""
+	}
+	return cs.Expression.String()
+}
+
+type PrintStatement struct {
+	Token token.Token // the token.PRINT token
+	File  Expression  // the file number of PRINT #, nil for the screen
+	Using Expression  // the format of PRINT USING, nil for a plain PRINT
+	Items []PrintItem
+}
+
+// PrintItem is a value of PRINT and the separator after it.
+type PrintItem struct {
+	Token     token.Token     // the first token of the item
+	Func      token.TokenType // token.TAB or token.SPC, "" for a plain value
+	Value     Expression      // nil if the item is a bare separator
+	Separator token.TokenType // token.SEMICOLON, token.COMMA or "" at the end
+}
+
+func (ps *PrintStatement) statementNode()       {}
+func (ps *PrintStatement) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PrintStatement) Pos() token.Position  { return ps.Token.Pos }
+func (ps *PrintStatement) String() string {
 	var out bytes.Buffer
 
-	if cs.Expression != nil {
-		out.WriteString(cs.Expression.String())
+	out.WriteString("PRINT")
+	if ps.File != nil {
+		out.WriteString(" #" + ps.File.String() + ",")
+	}
+	if ps.Using != nil {
+		out.WriteString(" USING ")
+		out.WriteString(ps.Using.String())
 		out.WriteString(";")
 	}
+	if len(ps.Items) > 0 {
+		out.WriteString(" ")
+	}
+	for _, item := range ps.Items {
+		switch {
+		case item.Func != "":
+			out.WriteString(string(item.Func) + "(" + item.Value.String() + ")")
+		case item.Value != nil:
+			out.WriteString(item.Value.String())
+		}
+		out.WriteString(string(item.Separator))
+	}
+
+	return out.String()
+}
+
+type InputStatement struct {
+	Token    token.Token    // the token.INPUT token
+	File     Expression     // the file number of INPUT #, nil for the keyboard
+	Prompt   *StringLiteral // nil if there is no prompt
+	Question bool           // whether "? " follows the prompt
+	Names    []*Identifier
+}
+
+func (is *InputStatement) statementNode()       {}
+func (is *InputStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *InputStatement) Pos() token.Position  { return is.Token.Pos }
+func (is *InputStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("INPUT ")
+	if is.File != nil {
+		out.WriteString("#" + is.File.String() + ", ")
+	}
+	if is.Prompt != nil {
+		out.WriteString(is.Prompt.String())
+		if is.Question {
+			out.WriteString("; ")
+		} else {
+			out.WriteString(", ")
+		}
+	}
+	names := []string{}
+	for _, n := range is.Names {
+		names = append(names, n.String())
+	}
+	out.WriteString(strings.Join(names, ", "))
+
+	return out.String()
+}
+
+type LineInputStatement struct {
+	Token  token.Token    // the token.LINE token
+	File   Expression     // the file number of LINE INPUT #, nil for the keyboard
+	Prompt *StringLiteral // nil if there is no prompt
+	Name   *Identifier
+}
+
+func (ls *LineInputStatement) statementNode()       {}
+func (ls *LineInputStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LineInputStatement) Pos() token.Position  { return ls.Token.Pos }
+func (ls *LineInputStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("LINE INPUT ")
+	if ls.File != nil {
+		out.WriteString("#" + ls.File.String() + ", ")
+	}
+	if ls.Prompt != nil {
+		out.WriteString(ls.Prompt.String())
+		out.WriteString("; ")
+	}
+	out.WriteString(ls.Name.String())
+
+	return out.String()
+}
+
+// OpenStatement is OPEN name FOR mode AS #n, or OPEN mode, #n, name.
+type OpenStatement struct {
+	Token  token.Token // the token.OPEN token
+	Mode   Expression  // "I", "O", "A" or "R"
+	Number Expression
+	Name   Expression
+	Length Expression // the record length, nil if omitted
+}
+
+func (os *OpenStatement) statementNode()       {}
+func (os *OpenStatement) TokenLiteral() string { return os.Token.Literal }
+func (os *OpenStatement) Pos() token.Position  { return os.Token.Pos }
+func (os *OpenStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("OPEN ")
+	out.WriteString(os.Mode.String())
+	out.WriteString(", #")
+	out.WriteString(os.Number.String())
+	out.WriteString(", ")
+	out.WriteString(os.Name.String())
+	if os.Length != nil {
+		out.WriteString(", ")
+		out.WriteString(os.Length.String())
+	}
+
+	return out.String()
+}
+
+type CloseStatement struct {
+	Token   token.Token  // the token.CLOSE token
+	Numbers []Expression // empty to close all the files
+}
+
+func (cs *CloseStatement) statementNode()       {}
+func (cs *CloseStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *CloseStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *CloseStatement) String() string {
+	numbers := []string{}
+	for _, n := range cs.Numbers {
+		numbers = append(numbers, "#"+n.String())
+	}
+	if len(numbers) == 0 {
+		return "CLOSE"
+	}
+	return "CLOSE " + strings.Join(numbers, ", ")
+}
+
+type WriteStatement struct {
+	Token  token.Token // the token.WRITE token
+	File   Expression  // the file number of WRITE #, nil for the screen
+	Values []Expression
+}
+
+func (ws *WriteStatement) statementNode()       {}
+func (ws *WriteStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WriteStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WriteStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("WRITE")
+	if ws.File != nil {
+		out.WriteString(" #" + ws.File.String() + ",")
+	}
+	values := []string{}
+	for _, v := range ws.Values {
+		values = append(values, v.String())
+	}
+	if len(values) > 0 {
+		out.WriteString(" " + strings.Join(values, ", "))
+	}
+
+	return out.String()
+}
+
+type KillStatement struct {
+	Token token.Token // the token.KILL token
+	Name  Expression
+}
+
+func (ks *KillStatement) statementNode()       {}
+func (ks *KillStatement) TokenLiteral() string { return ks.Token.Literal }
+func (ks *KillStatement) Pos() token.Position  { return ks.Token.Pos }
+func (ks *KillStatement) String() string       { return "KILL " + ks.Name.String() }
+
+type NameStatement struct {
+	Token token.Token // the token.NAME token
+	Old   Expression
+	New   Expression
+}
+
+func (ns *NameStatement) statementNode()       {}
+func (ns *NameStatement) TokenLiteral() string { return ns.Token.Literal }
+func (ns *NameStatement) Pos() token.Position  { return ns.Token.Pos }
+func (ns *NameStatement) String() string {
+	return "NAME " + ns.Old.String() + " AS " + ns.New.String()
+}
+
+// FieldStatement is FIELD #n, width AS name, ..., which lays out the
+// record of a random file.
+type FieldStatement struct {
+	Token  token.Token // the token.FIELD token
+	File   Expression
+	Widths []Expression
+	Names  []*Identifier
+}
+
+func (fs *FieldStatement) statementNode()       {}
+func (fs *FieldStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FieldStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *FieldStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("FIELD #" + fs.File.String())
+	for i, w := range fs.Widths {
+		out.WriteString(", " + w.String() + " AS " + fs.Names[i].String())
+	}
 
 	return out.String()
 }
 
+type RecordStatement struct {
+	Token  token.Token // the token.GET or token.PUT token
+	File   Expression
+	Record Expression // nil for the next record
+}
+
+func (rs *RecordStatement) statementNode()       {}
+func (rs *RecordStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RecordStatement) Pos() token.Position  { return rs.Token.Pos }
+func (rs *RecordStatement) String() string {
+	if rs.Record == nil {
+		return rs.Token.Literal + " #" + rs.File.String()
+	}
+	return rs.Token.Literal + " #" + rs.File.String() + ", " + rs.Record.String()
+}
+
+type SetStatement struct {
+	Token token.Token // the token.LSET or token.RSET token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ss *SetStatement) statementNode()       {}
+func (ss *SetStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SetStatement) Pos() token.Position  { return ss.Token.Pos }
+func (ss *SetStatement) String() string {
+	return ss.Token.Literal + " " + ss.Name.String() + " = " + ss.Value.String()
+}
+
 // Expressions
 type Identifier struct {
 	Token   token.Token // the token.IDENT token
@@ -412,20 +951,36 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 func (i *Identifier) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(i.Value) // TODO: $ を置換しないと
-	l := len(i.Indices)
-	for j := 0; j < l; j++ { // TODO: x,y が逆かも
-		out.WriteString("[")
-		out.WriteString(i.Indices[j].String())
-		out.WriteString("]")
+	out.WriteString(i.Value)
+	if len(i.Indices) > 0 {
+		indices := []string{}
+		for _, e := range i.Indices {
+			indices = append(indices, e.String())
+		}
+		out.WriteString("(")
+		out.WriteString(strings.Join(indices, ", "))
+		out.WriteString(")")
 	}
 
 	return out.String()
 }
 
+// IsLineNo reports whether the identifier names a line number
+// rather than a label or a variable.
+func (i *Identifier) IsLineNo() bool {
+	return len(i.Value) > 0 && '0' <= i.Value[0] && i.Value[0] <= '9'
+}
+
+// IsFn reports whether the identifier names a user function, which is
+// FN followed by a variable name: FNA, FNA$.
+func (i *Identifier) IsFn() bool {
+	return strings.HasPrefix(i.Value, "FN") && len(types.BaseName(i.Value)) > 2
+}
+
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
@@ -433,8 +988,20 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+	Type  types.Type // types.Single or types.Double
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -442,15 +1009,8 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
-func (sl *StringLiteral) String() string {
-	var out bytes.Buffer
-
-	out.WriteString("\"")
-	out.WriteString(sl.Value)
-	out.WriteString("\"")
-
-	return out.String()
-}
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) String() string       { return "\"" + sl.Value + "\"" }
 
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, e.g. -
@@ -460,15 +1020,17 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
-	out.WriteString("(")
+	if pe.Token.Type != token.MINUS {
+		out.WriteString(" ")
+	}
 	out.WriteString(pe.Right.String())
 	out.WriteString(")")
-	out.WriteString(")")
 
 	return out.String()
 }
@@ -482,26 +1044,13 @@ type InfixExpression struct {
 
 func (oe *InfixExpression) expressionNode()      {}
 func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
+func (oe *InfixExpression) Pos() token.Position  { return oe.Token.Pos }
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 
-	var op string
-	switch oe.Operator {
-	case token.OR:
-		op = "||"
-	case token.AND:
-		op = "&&"
-	case token.EQ:
-		op = "=="
-	case token.NOT_EQ:
-		op = "!="
-	default:
-		op = oe.Operator
-	}
-
 	out.WriteString("(")
 	out.WriteString(oe.Left.String())
-	out.WriteString(" " + op + " ")
+	out.WriteString(" " + oe.Operator + " ")
 	out.WriteString(oe.Right.String())
 	out.WriteString(")")
 
@@ -516,6 +1065,7 @@ type CallExpression struct {
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Pos }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
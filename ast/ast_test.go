@@ -47,7 +47,41 @@ func TestString(t *testing.T) {
 		},
 	}
 
-	if program.String() != "let myVar = anotherVar;" {
+	if program.String() != "myVar = anotherVar" {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestStringListing(t *testing.T) {
+	lineNo := func(n string) *LineNoStatement {
+		tok := token.Token{Type: token.LINENO, Literal: n}
+		return &LineNoStatement{Token: tok, Name: &Identifier{Token: tok, Value: n}}
+	}
+	ident := func(n string) *Identifier {
+		return &Identifier{Token: token.Token{Type: token.IDENT, Literal: n}, Value: n}
+	}
+
+	program := &Program{
+		Statements: []Statement{
+			lineNo("10"),
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "LET"},
+				Name:  ident("A"),
+				Value: &InfixExpression{
+					Token:    token.Token{Type: token.PLUS, Literal: "+"},
+					Left:     ident("B"),
+					Operator: "+",
+					Right:    &IntegerLiteral{Token: token.Token{Type: token.NUM, Literal: "1"}, Value: 1},
+				},
+			},
+			&GosubStatement{Token: token.Token{Type: token.GOSUB, Literal: "GOSUB"}, Name: ident("SUB")},
+			lineNo("20"),
+			&GotoStatement{Token: token.Token{Type: token.GOTO, Literal: "GOTO"}, Name: ident("10")},
+		},
+	}
+
+	expected := "10 A = (B + 1):GOSUB *SUB\n20 GOTO 10"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
@@ -0,0 +1,59 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/lexer"
+	"github.com/ysh86/b2c/parser"
+)
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 FOR J=1 TO 2:NEXT J", "10 FOR J = 1 TO 2:NEXT J"},
+		{"10 FOR I=1 TO 9 STEP 2:PRINT I:NEXT", "10 FOR I = 1 TO 9 STEP 2:PRINT I:NEXT"},
+		{"10 FOR I=1 TO 3\n20 PRINT I\n30 NEXT I", "10 FOR I = 1 TO 3\n20 PRINT I\n30 NEXT I"},
+		{"10 FOR I=1 TO 3:IF I<N THEN NEXT:PRINT", "10 FOR I = 1 TO 3:IF (I < N) THEN NEXT:PRINT"},
+		{"10 WHILE A:WEND", "10 WHILE A:WEND"},
+		{"10 IF A THEN B=1 ELSE C=1", "10 IF A THEN B = 1 ELSE C = 1"},
+		{
+			"80 IF A THEN\n90 B=1\n100 ELSEIF B THEN\n110 ELSE\n120 END IF",
+			"80 IF A THEN\n90 B = 1\n100 ELSEIF B THEN\n110 ELSE\n120 END IF",
+		},
+		{
+			"10 IF A THEN\n20 B=1:C=2\n30 ELSE\n40 IF B THEN\n50 END IF\n60 END IF",
+			"10 IF A THEN\n20 B = 1:C = 2\n30 ELSE\n40 IF B THEN\n50 END IF\n60 END IF",
+		},
+		{"IF A THEN\nB=1\nELSE\nEND IF", "IF A THEN\nB = 1\nEND IF"},
+		{"10 DO\n20 A=A+1\n30 LOOP UNTIL A", "10 DO\n20 A = (A + 1)\n30 LOOP UNTIL A"},
+	}
+
+	for _, tt := range tests {
+		got := parse(t, tt.input).String()
+		if got != tt.expected {
+			t.Errorf("String() wrong for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+
+		// the listing is BASIC that parses back to itself
+		if again := parse(t, got).String(); again != got {
+			t.Errorf("String() does not round-trip. expected=%q, got=%q", got, again)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(strings.NewReader(input)))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("parser error for %q: %v", input, err)
+	}
+
+	return program
+}
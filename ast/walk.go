@@ -0,0 +1,189 @@
+package ast
+
+// Inspect traverses the AST in depth-first order: it starts by calling
+// f(node); if f returns true, Inspect is invoked recursively for each of
+// the non-nil children of node.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	for _, c := range children(node) {
+		Inspect(c, f)
+	}
+}
+
+func children(node Node) []Node {
+	nodes := []Node{}
+	add := func(n ...Node) {
+		for _, c := range n {
+			if c != nil {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+	addStatements := func(stmts []Statement) {
+		for _, s := range stmts {
+			add(s)
+		}
+	}
+	addExpressions := func(exps []Expression) {
+		for _, e := range exps {
+			add(e)
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		addStatements(n.Statements)
+	case *LineNoStatement:
+		add(n.Name)
+	case *LabelStatement:
+		add(n.Name)
+	case *DimStatement:
+		for i, name := range n.Names {
+			add(name)
+			for _, v := range n.Values[i] {
+				add(v)
+			}
+		}
+	case *DefFnStatement:
+		add(n.Name)
+		for _, param := range n.Parameters {
+			add(param)
+		}
+		add(n.Value)
+	case *IfStatement:
+		add(n.Condition)
+		addStatements(n.Consequence)
+		addStatements(n.Alternative)
+	case *OnStatement:
+		add(n.Value)
+		for _, name := range n.Names {
+			add(name)
+		}
+	case *GotoStatement:
+		add(n.Name)
+	case *GosubStatement:
+		add(n.Name)
+	case *OnErrorStatement:
+		if n.Name != nil {
+			add(n.Name)
+		}
+	case *ResumeStatement:
+		if n.Name != nil {
+			add(n.Name)
+		}
+	case *ErrorStatement:
+		add(n.Value)
+	case *ForStatement:
+		add(n.Name, n.Begin, n.End, n.Step)
+		addStatements(n.Statements)
+		if n.Next != nil {
+			add(n.Next)
+		}
+	case *NextStatement:
+		if n.Name != nil {
+			add(n.Name)
+		}
+	case *WhileStatement:
+		add(n.Condition)
+		addStatements(n.Statements)
+	case *DoStatement:
+		add(n.Condition)
+		addStatements(n.Statements)
+	case *ReadStatement:
+		for _, name := range n.Names {
+			add(name)
+		}
+	case *RestoreStatement:
+		if n.Name != nil {
+			add(n.Name)
+		}
+	case *RandomizeStatement:
+		if n.Value != nil {
+			add(n.Value)
+		}
+	case *SwapStatement:
+		add(n.Left, n.Right)
+	case *EraseStatement:
+		for _, name := range n.Names {
+			add(name)
+		}
+	case *LetStatement:
+		add(n.Name, n.Value)
+	case *CallStatement:
+		if n.Expression != nil {
+			add(n.Expression)
+		}
+	case *OpenStatement:
+		add(n.Mode, n.Number, n.Name)
+		if n.Length != nil {
+			add(n.Length)
+		}
+	case *CloseStatement:
+		addExpressions(n.Numbers)
+	case *WriteStatement:
+		if n.File != nil {
+			add(n.File)
+		}
+		addExpressions(n.Values)
+	case *KillStatement:
+		add(n.Name)
+	case *NameStatement:
+		add(n.Old, n.New)
+	case *FieldStatement:
+		add(n.File)
+		for i, w := range n.Widths {
+			add(w, n.Names[i])
+		}
+	case *RecordStatement:
+		add(n.File)
+		if n.Record != nil {
+			add(n.Record)
+		}
+	case *SetStatement:
+		add(n.Name, n.Value)
+	case *PrintStatement:
+		if n.File != nil {
+			add(n.File)
+		}
+		if n.Using != nil {
+			add(n.Using)
+		}
+		for _, item := range n.Items {
+			if item.Value != nil {
+				add(item.Value)
+			}
+		}
+	case *InputStatement:
+		if n.File != nil {
+			add(n.File)
+		}
+		if n.Prompt != nil {
+			add(n.Prompt)
+		}
+		for _, name := range n.Names {
+			add(name)
+		}
+	case *LineInputStatement:
+		if n.File != nil {
+			add(n.File)
+		}
+		if n.Prompt != nil {
+			add(n.Prompt)
+		}
+		add(n.Name)
+	case *Identifier:
+		addExpressions(n.Indices)
+	case *PrefixExpression:
+		add(n.Right)
+	case *InfixExpression:
+		add(n.Left, n.Right)
+	case *CallExpression:
+		add(n.Function)
+		addExpressions(n.Arguments)
+	}
+
+	return nodes
+}
@@ -0,0 +1,119 @@
+// Package builtin describes the functions built into BASIC.
+package builtin
+
+import "github.com/ysh86/b2c/types"
+
+// Kind is the kind of an argument.
+type Kind int
+
+const (
+	Numeric Kind = iota
+	String
+)
+
+// Func is a builtin function.
+type Func struct {
+	Name   string
+	Result types.Type // Invalid for the type of the first argument
+	Sigs   [][]Kind   // the accepted lists of arguments
+}
+
+var funcs = map[string]*Func{}
+
+func register(name string, result types.Type, sigs ...[]Kind) {
+	funcs[name] = &Func{Name: name, Result: result, Sigs: sigs}
+}
+
+func args(kinds ...Kind) []Kind { return kinds }
+
+func init() {
+	// strings
+	register("LEN", types.Integer, args(String))
+	register("ASC", types.Integer, args(String))
+	register("CHR$", types.String, args(Numeric))
+	register("MID$", types.String, args(String, Numeric), args(String, Numeric, Numeric))
+	register("LEFT$", types.String, args(String, Numeric))
+	register("RIGHT$", types.String, args(String, Numeric))
+	register("STR$", types.String, args(Numeric))
+	register("VAL", types.Single, args(String))
+	register("INSTR", types.Integer, args(String, String), args(Numeric, String, String))
+	register("STRING$", types.String, args(Numeric, Numeric), args(Numeric, String))
+	register("SPACE$", types.String, args(Numeric))
+	register("HEX$", types.String, args(Numeric))
+	register("OCT$", types.String, args(Numeric))
+
+	// numbers
+	register("ABS", types.Invalid, args(Numeric))
+	register("INT", types.Invalid, args(Numeric))
+	register("FIX", types.Invalid, args(Numeric))
+	register("SGN", types.Integer, args(Numeric))
+	register("SQR", types.Single, args(Numeric))
+	register("SIN", types.Single, args(Numeric))
+	register("COS", types.Single, args(Numeric))
+	register("TAN", types.Single, args(Numeric))
+	register("ATN", types.Single, args(Numeric))
+	register("LOG", types.Single, args(Numeric))
+	register("EXP", types.Single, args(Numeric))
+	register("RND", types.Single, args(), args(Numeric))
+	register("CINT", types.Integer, args(Numeric))
+	register("CSNG", types.Single, args(Numeric))
+	register("CDBL", types.Double, args(Numeric))
+
+	// files
+	register("EOF", types.Integer, args(Numeric))
+	register("LOF", types.Single, args(Numeric))
+	register("MKI$", types.String, args(Numeric))
+	register("MKS$", types.String, args(Numeric))
+	register("MKD$", types.String, args(Numeric))
+	register("CVI", types.Integer, args(String))
+	register("CVS", types.Single, args(String))
+	register("CVD", types.Double, args(String))
+
+	// errors
+	register("ERR", types.Integer, args())
+	register("ERL", types.Single, args()) // up to 65529
+}
+
+// Lookup returns the builtin function name, or nil if there is none.
+func Lookup(name string) *Func {
+	return funcs[name]
+}
+
+// Type returns the type of the result of f called with args.
+func (f *Func) Type(args []types.Type) types.Type {
+	if f.Result == types.Invalid && len(args) > 0 {
+		return args[0]
+	}
+	return f.Result
+}
+
+// Match returns the signature of f that takes args, or nil if f cannot be
+// called with args.
+func (f *Func) Match(args []types.Type) []Kind {
+	for _, sig := range f.Sigs {
+		if len(sig) != len(args) {
+			continue
+		}
+		ok := true
+		for i, k := range sig {
+			if (k == String) != (args[i] == types.String) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return sig
+		}
+	}
+	return nil
+}
+
+// Arity reports whether f takes n arguments in any of its signatures.
+func (f *Func) Arity(n int) bool {
+	for _, sig := range f.Sigs {
+		if len(sig) == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/types"
+)
+
+// The arrays are static, so ERASE and CLEAR cannot free them. They reset
+// the values instead, and free the bytes of the strings.
+
+// erase returns the statements that reset the variable sym.
+func (g *Generator) erase(sym *semantic.Symbol) []string {
+	if sym.IsArray() {
+		name := arrayName(sym.Name)
+		if sym.Type == types.String {
+			g.require("str")
+			return []string{"b2c_str_erase(&" + name + firstElement(sym) + ", (int)(sizeof " + name + " / sizeof(b2c_str)));"}
+		}
+		return []string{"memset(" + name + ", 0, sizeof " + name + ");"}
+	}
+
+	name := scalarName(sym.Name)
+	if sym.Type == types.String {
+		g.require("str")
+		return []string{"b2c_str_erase(&" + name + ", 1);"}
+	}
+	return []string{name + " = 0;"}
+}
+
+// firstElement returns the subscripts of the first element of sym: [0][0].
+func firstElement(sym *semantic.Symbol) string {
+	s := ""
+	for range sym.Dims {
+		s += "[0]"
+	}
+	return s
+}
+
+func (g *Generator) eraseStatement(s *ast.EraseStatement) {
+	for _, name := range s.Names {
+		sym := g.table.Arrays[types.Canonical(name.Value)]
+		if sym == nil {
+			g.errorf(name, "array %s is not declared by DIM", name.Value)
+			return
+		}
+		for _, line := range g.erase(sym) {
+			g.e.line("%s", line)
+		}
+	}
+}
+
+// clearStatement calls b2c_clear, which resets all the variables and
+// closes the files.
+func (g *Generator) clearStatement(s *ast.ClearStatement) {
+	if g.clearBody == nil {
+		g.clearBody = []string{}
+		for _, sym := range g.table.ScalarList() {
+			g.clearBody = append(g.clearBody, g.erase(sym)...)
+		}
+		for _, sym := range g.table.ArrayList() {
+			g.clearBody = append(g.clearBody, g.erase(sym)...)
+		}
+	}
+	g.e.line("b2c_clear();")
+}
+
+// clearFunction writes b2c_clear if CLEAR is used.
+func (g *Generator) clearFunction(out *emitter) {
+	if g.clearBody == nil {
+		return
+	}
+	out.line("static void b2c_clear(void)")
+	out.line("{")
+	out.in()
+	for _, line := range g.clearBody {
+		out.line("%s", line)
+	}
+	if g.required["file"] {
+		out.line("b2c_close_all();")
+	}
+	out.out()
+	out.line("}")
+	out.blank()
+}
+
+// swapStatement exchanges two variables of the same type. The strings
+// exchange their bytes too, so nothing is copied.
+func (g *Generator) swapStatement(s *ast.SwapStatement) {
+	t := semantic.TypeOf(s.Left)
+	left, right := g.expression(s.Left), g.expression(s.Right)
+
+	g.e.line("{")
+	g.e.in()
+	g.e.line("%s = %s;", cDecl(t, "b2c_t"), left)
+	g.e.line("%s = %s;", left, right)
+	g.e.line("%s = b2c_t;", right)
+	g.e.out()
+	g.e.line("}")
+}
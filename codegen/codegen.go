@@ -0,0 +1,829 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/builtin"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/token"
+	"github.com/ysh86/b2c/types"
+)
+
+// Error is reported when a node cannot be translated into C.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// DefaultGosubDepth is the default size of the GOSUB return stack.
+const DefaultGosubDepth = 256
+
+// Generator translates an *ast.Program into C.
+type Generator struct {
+	// GosubDepth is the size of the GOSUB return stack.
+	// If it is 0, DefaultGosubDepth is used.
+	GosubDepth int
+
+	e   *emitter
+	err *Error // the first error
+
+	table    *semantic.Table
+	targets  map[string]bool // defined line numbers and labels
+	required map[string]bool // runtime sections
+
+	returnPoints []int // ids of the GOSUB return points
+	hasReturn    bool
+
+	temps   []string  // declarations of the temporaries
+	loops   int       // number of FOR and DO loops
+	doLoops []*doLoop // the DO loops around the current statement
+
+	data      []datum        // the items of all DATA statements
+	dataIndex map[string]int // the first item at or after each line and label
+
+	trapping      bool           // ON ERROR GOTO is used
+	stmts         int            // number of statements, see resumePoint()
+	handlers      map[string]int // ids of the ON ERROR GOTO handlers
+	handlerLabels []string       // the handlers by id - 1
+	resumes       bool           // RESUME is used
+	resumesNext   bool           // RESUME NEXT is used
+
+	clearBody []string // the statements of b2c_clear, nil without CLEAR
+}
+
+func New() *Generator {
+	return &Generator{
+		e:         &emitter{},
+		table:     semantic.NewTable(),
+		targets:   make(map[string]bool),
+		required:  make(map[string]bool),
+		dataIndex: make(map[string]int),
+		handlers:  make(map[string]int),
+	}
+}
+
+func (g *Generator) gosubDepth() int {
+	if g.GosubDepth > 0 {
+		return g.GosubDepth
+	}
+	return DefaultGosubDepth
+}
+
+// Generate writes program to w as a complete C translation unit.
+func (g *Generator) Generate(w io.Writer, program *ast.Program) error {
+	table, err := semantic.Analyze(program)
+	if err != nil {
+		if e, ok := err.(*semantic.Error); ok {
+			return &Error{Pos: e.Pos, Msg: e.Msg}
+		}
+		return err
+	}
+	g.table = table
+
+	g.require("core")
+	g.collect(program)
+	g.checkTargets(program)
+
+	g.e.in()
+	g.errorTrap()
+	g.statements(program.Statements)
+	g.noResume()
+	g.e.out()
+	funcs := g.functions()
+	if g.err != nil {
+		return g.err
+	}
+
+	out := &emitter{}
+	out.raw(prologue)
+	g.runtime(out)
+	out.blank()
+	g.globals(out)
+	g.clearFunction(out)
+	out.raw(funcs)
+	out.line("int main(void)")
+	out.line("{")
+	out.raw(g.e.String())
+	out.raw(epilogue)
+	g.returnDispatcher(out)
+	g.errorDispatchers(out)
+	out.line("}")
+
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+// GenerateFragment writes only the statements of program to w.
+// It is used by the REPL, where jump targets may be on other lines.
+func (g *Generator) GenerateFragment(w io.Writer, program *ast.Program) error {
+	g.statements(program.Statements)
+	if g.err != nil {
+		return g.err
+	}
+
+	_, err := io.WriteString(w, g.e.String())
+	return err
+}
+
+func (g *Generator) errorf(node ast.Node, format string, a ...interface{}) {
+	if g.err != nil {
+		return
+	}
+	g.err = &Error{Pos: node.Pos(), Msg: fmt.Sprintf(format, a...)}
+}
+
+// ------------------------------------------------------------
+// Statements
+// ------------------------------------------------------------
+
+func (g *Generator) statements(stmts []ast.Statement) {
+	for _, s := range stmts {
+		g.statement(s)
+	}
+}
+
+func (g *Generator) block(stmts []ast.Statement) {
+	g.e.in()
+	g.statements(stmts)
+	g.e.out()
+}
+
+// loopBody writes the statements of a loop starting at tok. If they
+// span several lines, the loop goes back to the line of tok, which is
+// restored for the error messages.
+func (g *Generator) loopBody(tok token.Token, stmts []ast.Statement) {
+	g.e.in()
+	if tok.Pos.LineNo != 0 && len(stmts) > 0 {
+		if _, ok := stmts[0].(*ast.LineNoStatement); !ok && hasLineNo(stmts) {
+			g.e.line("b2c_line = %d;", tok.Pos.LineNo)
+		}
+	}
+	g.statements(stmts)
+	g.e.out()
+}
+
+// hasLineNo reports whether stmts have a line number.
+func hasLineNo(stmts []ast.Statement) bool {
+	found := false
+	for _, s := range stmts {
+		ast.Inspect(s, func(n ast.Node) bool {
+			if _, ok := n.(*ast.LineNoStatement); ok {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+func (g *Generator) statement(s ast.Statement) {
+	if next := g.resumePoint(s); next != "" {
+		defer g.e.line("%s:;", next)
+	}
+
+	switch s := s.(type) {
+	case *ast.LineNoStatement:
+		g.lineNoStatement(s)
+	case *ast.LabelStatement:
+		g.labelStatement(s)
+	case *ast.DimStatement:
+		g.e.line("// %s", s.String()) // hoisted by globals()
+	case *ast.DataStatement:
+		g.e.line("// %s", s.String()) // see dataTable()
+	case *ast.DefFnStatement:
+		g.e.line("%s = 1; // %s", defName(s.Name.Value), s.String()) // see functions()
+	case *ast.ReadStatement:
+		g.readStatement(s)
+	case *ast.RestoreStatement:
+		g.restoreStatement(s)
+	case *ast.IfStatement:
+		g.ifStatement(s)
+	case *ast.OnStatement:
+		g.onStatement(s)
+	case *ast.OnErrorStatement:
+		g.onErrorStatement(s)
+	case *ast.ResumeStatement:
+		g.resumeStatement(s)
+	case *ast.ErrorStatement:
+		g.e.line("b2c_raise(%s);", g.intExpression(s.Value))
+	case *ast.GotoStatement:
+		g.e.line("goto %s;", labelName(s.Name))
+	case *ast.GosubStatement:
+		g.gosub(s.Name)
+	case *ast.ReturnStatement:
+		g.require("gosub")
+		g.hasReturn = true
+		g.e.line("goto b2c_return;")
+	case *ast.ForStatement:
+		g.forStatement(s)
+	case *ast.NextStatement:
+		g.e.line("continue;") // the IF is in the body of the FOR
+	case *ast.WhileStatement:
+		g.e.line("while (%s) {", g.condition(s.Condition))
+		g.loopBody(s.Token, s.Statements)
+		g.e.line("}")
+	case *ast.DoStatement:
+		g.doStatement(s)
+	case *ast.ExitStatement:
+		g.exitStatement(s)
+	case *ast.RandomizeStatement:
+		g.randomizeStatement(s)
+	case *ast.EndStatement:
+		g.e.line("b2c_end();")
+	case *ast.StopStatement:
+		g.e.line("b2c_stop();")
+	case *ast.ClearStatement:
+		g.clearStatement(s)
+	case *ast.SwapStatement:
+		g.swapStatement(s)
+	case *ast.EraseStatement:
+		g.eraseStatement(s)
+	case *ast.LetStatement:
+		g.assign(s.Name, g.convert(s.Value, semantic.TypeOf(s.Name)))
+	case *ast.PrintStatement:
+		g.printStatement(s)
+	case *ast.InputStatement:
+		g.inputStatement(s)
+	case *ast.LineInputStatement:
+		g.lineInputStatement(s)
+	case *ast.WriteStatement:
+		g.writeStatement(s)
+	case *ast.OpenStatement:
+		g.openStatement(s)
+	case *ast.CloseStatement:
+		g.closeStatement(s)
+	case *ast.KillStatement:
+		g.killStatement(s)
+	case *ast.NameStatement:
+		g.nameStatement(s)
+	case *ast.FieldStatement:
+		g.fieldStatement(s)
+	case *ast.RecordStatement:
+		g.recordStatement(s)
+	case *ast.SetStatement:
+		g.setStatement(s)
+	case *ast.CallStatement:
+		g.errorf(s, "unsupported statement: %s", s.Expression.Function.Value)
+	default:
+		g.errorf(s, "unsupported statement: %s", s.TokenLiteral())
+	}
+}
+
+// assign writes the assignment of the C expression value to the variable
+// name. A string is copied into the storage of the variable.
+func (g *Generator) assign(name *ast.Identifier, value string) {
+	if semantic.TypeOf(name) == types.String {
+		g.e.line("b2c_str_assign(&%s, %s);", g.expression(name), value)
+		return
+	}
+	g.e.line("%s = %s;", g.expression(name), value)
+}
+
+// functions returns the user functions as static C functions. Their
+// parameters are locals, which hide the global variables of the same
+// names in the body. A function called before its DEF has run raises
+// Undefined user function.
+func (g *Generator) functions() string {
+	funcs := g.table.FuncList()
+	if len(funcs) == 0 {
+		return ""
+	}
+
+	main := g.e
+	g.e = &emitter{}
+	defer func() { g.e = main }()
+
+	signature := func(f *ast.DefFnStatement) string {
+		params := []string{}
+		for _, p := range f.Parameters {
+			params = append(params, cDecl(semantic.TypeOf(p), scalarName(p.Value)))
+		}
+		if len(params) == 0 {
+			params = append(params, "void")
+		}
+		t := semantic.TypeOf(f.Name)
+		return "static " + cDecl(t, funcName(f.Name.Value)) + "(" + strings.Join(params, ", ") + ")"
+	}
+
+	for _, f := range funcs {
+		g.e.line("static int %s;", defName(f.Name.Value))
+	}
+	// the functions may call each other
+	for _, f := range funcs {
+		g.e.line("%s;", signature(f))
+	}
+	for _, f := range funcs {
+		g.e.blank()
+		g.e.line("// %s", f.String())
+		g.e.line("%s", signature(f))
+		g.e.line("{")
+		g.e.in()
+		g.e.line("if (!%s) {", defName(f.Name.Value))
+		g.e.in()
+		g.e.line("b2c_error(B2C_E_UNDEFINED_FN);")
+		g.e.out()
+		g.e.line("}")
+		g.e.line("return %s;", g.convert(f.Value, semantic.TypeOf(f.Name)))
+		g.e.out()
+		g.e.line("}")
+	}
+	g.e.blank()
+
+	return g.e.String()
+}
+
+func (g *Generator) lineNoStatement(s *ast.LineNoStatement) {
+	g.e.line("%s: b2c_line = %s;", labelName(s.Name), s.Name.Value)
+}
+
+func (g *Generator) labelStatement(s *ast.LabelStatement) {
+	g.e.blank()
+	g.e.line("// -----------------------------------")
+	g.e.line("%s:;", labelName(s.Name))
+}
+
+func (g *Generator) ifStatement(s *ast.IfStatement) {
+	g.e.line("if (%s) {", g.condition(s.Condition))
+	g.block(s.Consequence)
+	for s.ElseIf() != nil {
+		s = s.ElseIf()
+		g.e.line("} else if (%s) {", g.condition(s.Condition))
+		g.block(s.Consequence)
+	}
+	if len(s.Alternative) != 0 {
+		g.e.line("} else {")
+		g.block(s.Alternative)
+	}
+	g.e.line("}")
+}
+
+func (g *Generator) onStatement(s *ast.OnStatement) {
+	g.e.line("switch (%s) {", g.intExpression(s.Value))
+	for i, n := range s.Names {
+		g.e.line("case %d:", i+1) // 1 origin
+		g.e.in()
+		if s.Instruction.Type == token.GOTO {
+			g.e.line("goto %s;", labelName(n))
+		} else {
+			g.gosub(n)
+		}
+		g.e.line("break;")
+		g.e.out()
+	}
+	g.e.line("default:")
+	g.e.in()
+	g.e.line("// nothing to do")
+	g.e.line("break;")
+	g.e.out()
+	g.e.line("}")
+}
+
+// gosub pushes a new return point and jumps to name.
+func (g *Generator) gosub(name *ast.Identifier) {
+	g.require("gosub")
+
+	id := len(g.returnPoints) + 1
+	g.returnPoints = append(g.returnPoints, id)
+
+	g.e.line("b2c_gosub_push(%d);", id)
+	g.e.line("goto %s;", labelName(name))
+	g.e.line("b2c_ret_%d:;", id)
+}
+
+// returnDispatcher writes the code that RETURN jumps to.
+func (g *Generator) returnDispatcher(out *emitter) {
+	if !g.hasReturn {
+		return
+	}
+
+	out.blank()
+	out.line("b2c_return:")
+	out.in()
+	out.line("switch (b2c_gosub_pop()) {")
+	for _, id := range g.returnPoints {
+		out.line("case %d: goto b2c_ret_%d;", id, id)
+	}
+	out.line("}")
+	out.line("return 0;")
+	out.out()
+}
+
+// forStatement evaluates the limit and the step once, before the first
+// iteration. The loop runs while the variable has not passed the limit
+// in the direction of the step, and the variable keeps the value that
+// ended the loop.
+func (g *Generator) forStatement(s *ast.ForStatement) {
+	g.loops++
+	id := g.loops
+
+	t := semantic.TypeOf(s.Name)
+	name := g.expression(s.Name)
+	end := fmt.Sprintf("b2c_for%d_end", id)
+	g.temps = append(g.temps, "static "+cDecl(t, end)+";")
+
+	g.e.line("%s = %s;", name, g.convert(s.Begin, t))
+	g.e.line("%s = %s;", end, g.convert(s.End, t))
+
+	stepExpr := s.Step
+	if stepExpr == nil {
+		stepExpr = &ast.IntegerLiteral{Token: token.Token{Type: token.NUM, Literal: "1", Pos: s.Token.Pos}, Value: 1}
+	}
+
+	var cond, step string
+	if v, ok := constValue(stepExpr); ok {
+		step = g.convert(stepExpr, t)
+		if v >= 0 {
+			cond = fmt.Sprintf("%s <= %s", name, end)
+		} else {
+			cond = fmt.Sprintf("%s >= %s", name, end)
+		}
+	} else {
+		step = fmt.Sprintf("b2c_for%d_step", id)
+		g.temps = append(g.temps, "static "+cDecl(t, step)+";")
+		g.e.line("%s = %s;", step, g.convert(stepExpr, t))
+		cond = fmt.Sprintf("%s >= 0 ? %s <= %s : %s >= %s", step, name, end, name, end)
+	}
+
+	g.e.line("for (; %s; %s += %s) {", cond, name, step)
+	if s.IfNext != nil {
+		g.forIfNext(s, id)
+		return
+	}
+	g.loopBody(s.Token, s.Statements)
+	g.e.line("}")
+}
+
+// forIfNext writes the body of the FOR s that ends at a NEXT in its last
+// statement, an IF. The NEXT continues the loop, and the loop is left if
+// the IF does not reach it. The statements after the NEXT run when the
+// loop ends:
+//
+//	for (; vf_I <= b2c_for1_end; vf_I += 1) {
+//	    if (c) {
+//	        continue;
+//	    }
+//	    goto b2c_for1_exit;
+//	}
+//	(the statements after NEXT)
+//	b2c_for1_exit:;
+func (g *Generator) forIfNext(s *ast.ForStatement, id int) {
+	last := len(s.Statements) - 1
+	is := *s.Statements[last].(*ast.IfStatement)
+
+	var after []ast.Statement
+	if i := indexOf(is.Consequence, s.IfNext); i >= 0 {
+		is.Consequence, after = is.Consequence[:i+1], is.Consequence[i+1:]
+	} else {
+		i = indexOf(is.Alternative, s.IfNext)
+		is.Alternative, after = is.Alternative[:i+1], is.Alternative[i+1:]
+	}
+
+	body := append(append([]ast.Statement{}, s.Statements[:last]...), &is)
+	g.loopBody(s.Token, body)
+	g.e.in()
+	g.e.line("goto b2c_for%d_exit;", id)
+	g.e.out()
+	g.e.line("}")
+
+	if line := is.Token.Pos.LineNo; line != 0 && line != s.Token.Pos.LineNo {
+		g.e.line("b2c_line = %d;", line) // the loop may not have run
+	}
+	g.statements(after)
+	g.e.line("b2c_for%d_exit:;", id)
+}
+
+// indexOf returns the index of s in stmts, or -1.
+func indexOf(stmts []ast.Statement, s ast.Statement) int {
+	for i, t := range stmts {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// doLoop is a DO loop being generated.
+type doLoop struct {
+	id     int
+	exited bool // EXIT DO jumps to the end of the loop
+}
+
+func (g *Generator) doStatement(s *ast.DoStatement) {
+	g.loops++
+	loop := &doLoop{id: g.loops}
+	g.doLoops = append(g.doLoops, loop)
+
+	var cond string
+	if s.Condition != nil {
+		cond = g.condition(s.Condition)
+		if s.Until {
+			cond = "!(" + cond + ")"
+		}
+	}
+
+	switch {
+	case s.Condition == nil:
+		g.e.line("for (;;) {")
+		g.loopBody(s.Token, s.Statements)
+		g.e.line("}")
+	case s.Post:
+		g.e.line("do {")
+		g.loopBody(s.Token, s.Statements)
+		g.e.line("} while (%s);", cond)
+	default:
+		g.e.line("while (%s) {", cond)
+		g.loopBody(s.Token, s.Statements)
+		g.e.line("}")
+	}
+
+	g.doLoops = g.doLoops[:len(g.doLoops)-1]
+	if loop.exited {
+		g.e.line("b2c_do%d_exit:;", loop.id)
+	}
+}
+
+// exitStatement jumps out of the innermost DO loop. It cannot be a break,
+// which would leave a FOR or WHILE loop inside the DO loop.
+func (g *Generator) exitStatement(s *ast.ExitStatement) {
+	if len(g.doLoops) == 0 {
+		g.errorf(s, "EXIT DO outside DO...LOOP")
+		return
+	}
+	loop := g.doLoops[len(g.doLoops)-1]
+	loop.exited = true
+	g.e.line("goto b2c_do%d_exit;", loop.id)
+}
+
+// constValue returns the value of a numeric constant such as 1 or -0.5.
+func constValue(e ast.Expression) (float64, bool) {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return float64(e.Value), true
+	case *ast.FloatLiteral:
+		return e.Value, true
+	case *ast.PrefixExpression:
+		if e.Token.Type == token.MINUS {
+			if v, ok := constValue(e.Right); ok {
+				return -v, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// ------------------------------------------------------------
+// Expressions
+// ------------------------------------------------------------
+
+func (g *Generator) expression(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return g.identifier(e)
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(e.Value, 10)
+	case *ast.FloatLiteral:
+		if e.Type == types.Single && float64(float32(e.Value)) != e.Value && math.Abs(e.Value) <= sngMax {
+			return cFloat(e.Value) + "f" // .1 is the single nearest to it
+		}
+		return cFloat(e.Value)
+	case *ast.StringLiteral:
+		return g.stringLiteral(e.Value)
+	case *ast.PrefixExpression:
+		if e.Token.Type == token.NOT {
+			if isBoolean(e) {
+				return "(-" + g.boolean(e) + ")"
+			}
+			return "(~" + g.intExpression(e.Right) + ")"
+		}
+		return "(" + e.Operator + "(" + g.expression(e.Right) + "))"
+	case *ast.InfixExpression:
+		return g.infixExpression(e)
+	case *ast.CallExpression:
+		return g.callExpression(e)
+	case nil:
+		return ""
+	default:
+		g.errorf(e, "unsupported expression: %s", e.TokenLiteral())
+		return ""
+	}
+}
+
+// intExpression returns e converted to a C int.
+func (g *Generator) intExpression(e ast.Expression) string {
+	return g.convert(e, types.Integer)
+}
+
+// convert returns e converted to the type t. A double converted to a
+// single is rounded and checked to be in the range of a single.
+func (g *Generator) convert(e ast.Expression, t types.Type) string {
+	if t == types.Integer && semantic.TypeOf(e) != types.Integer {
+		g.require("cint")
+		return "b2c_cint(" + g.expression(e) + ")"
+	}
+	if t == types.Single {
+		return g.single(e)
+	}
+	return g.expression(e)
+}
+
+// sngMax is the largest single of GW-BASIC, B2C_SNG_MAX of the runtime.
+const sngMax = 1.70141173e38
+
+// single returns the value of e as a single. A single expression is
+// already one, rounded and checked where it is computed, so only a double
+// and a constant out of range are converted by b2c_csng.
+func (g *Generator) single(e ast.Expression) string {
+	t := semantic.TypeOf(e)
+	if v, ok := constValue(e); (!ok || math.Abs(v) <= sngMax) && (t == types.Integer || t == types.Single) {
+		return g.expression(e)
+	}
+	return g.csng(g.expression(e))
+}
+
+// csng rounds the C expression x to a single, checking its range.
+func (g *Generator) csng(x string) string {
+	g.require("csng")
+	return "b2c_csng(" + x + ")"
+}
+
+func (g *Generator) identifier(i *ast.Identifier) string {
+	var out strings.Builder
+
+	if len(i.Indices) == 0 {
+		return scalarName(i.Value)
+	}
+
+	sym := g.table.Arrays[types.Canonical(i.Value)]
+	out.WriteString(arrayName(i.Value))
+	for k, e := range i.Indices { // TODO: x,y が逆かも
+		out.WriteString("[" + g.index(e, sym, k) + "]")
+	}
+
+	return out.String()
+}
+
+// index returns the k-th subscript e of the array sym. It is checked
+// unless it is a constant within the bounds.
+func (g *Generator) index(e ast.Expression, sym *semantic.Symbol, k int) string {
+	if sym == nil || k >= len(sym.Dims) {
+		return g.intExpression(e) // unknown in a fragment
+	}
+	if v, ok := constValue(e); ok && v == float64(int64(v)) && 0 <= v && v <= float64(sym.Dims[k]) {
+		return g.intExpression(e)
+	}
+	return "b2c_index(" + g.intExpression(e) + ", " + strconv.FormatInt(sym.Dims[k], 10) + ")"
+}
+
+// relationalOperators are the C operators of the comparisons.
+var relationalOperators = map[token.TokenType]string{
+	token.EQ:     "==",
+	token.NOT_EQ: "!=",
+	token.LT:     "<",
+	token.GT:     ">",
+	token.LT_EQ:  "<=",
+	token.GT_EQ:  ">=",
+}
+
+// logicalOperators are the C operators of the logical operators, which
+// work on the bits of 16-bit integers.
+var logicalOperators = map[token.TokenType]string{
+	token.AND: "&",
+	token.OR:  "|",
+	token.XOR: "^",
+}
+
+func (g *Generator) infixExpression(e *ast.InfixExpression) string {
+	if isBoolean(e) {
+		return "(-" + g.boolean(e) + ")" // true is -1 in BASIC
+	}
+
+	if semantic.TypeOf(e.Left) == types.String {
+		return g.stringInfix(e, e.Operator)
+	}
+
+	switch e.Token.Type {
+	case token.AND, token.OR, token.XOR:
+		op := logicalOperators[e.Token.Type]
+		return "(" + g.intExpression(e.Left) + " " + op + " " + g.intExpression(e.Right) + ")"
+	case token.EQV:
+		return "(~(" + g.intExpression(e.Left) + " ^ " + g.intExpression(e.Right) + "))"
+	case token.IMP:
+		return "(~" + g.intExpression(e.Left) + " | " + g.intExpression(e.Right) + ")"
+	case token.BACKSLASH:
+		g.require("math")
+		return "b2c_idiv(" + g.intExpression(e.Left) + ", " + g.intExpression(e.Right) + ")"
+	case token.MOD:
+		g.require("math")
+		return "b2c_mod(" + g.intExpression(e.Left) + ", " + g.intExpression(e.Right) + ")"
+	}
+
+	var exp string
+	left := g.expression(e.Left)
+	if v, ok := constValue(e.Right); e.Token.Type == token.CARET {
+		g.require("math")
+		exp = "b2c_pow(" + left + ", " + g.expression(e.Right) + ")"
+	} else if e.Token.Type == token.SLASH && (!ok || v == 0) {
+		g.require("math")
+		exp = "b2c_div(" + left + ", " + g.expression(e.Right) + ")"
+	} else {
+		if e.Token.Type == token.SLASH && semantic.TypeOf(e.Left) == types.Integer {
+			left = "(double)" + left // 1/2 is 0.5 in BASIC
+		}
+		exp = "(" + left + " " + e.Operator + " " + g.expression(e.Right) + ")"
+	}
+
+	switch semantic.TypeOf(e) {
+	case types.Integer:
+		// +, - and * of 16-bit integers cannot overflow a C int
+		g.require("int")
+		return "b2c_int" + exp
+	case types.Single:
+		// computed as a double, then rounded to a single
+		return g.csng(exp)
+	}
+	return exp
+}
+
+// condition returns e as the condition of a C statement.
+func (g *Generator) condition(e ast.Expression) string {
+	if isBoolean(e) {
+		return g.boolean(e)
+	}
+	return g.expression(e)
+}
+
+// isBoolean reports whether e is a comparison, or a logical operation on
+// comparisons, so that its value is either -1 or 0.
+func isBoolean(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		if _, ok := relationalOperators[e.Token.Type]; ok {
+			return true
+		}
+		if _, ok := logicalOperators[e.Token.Type]; ok {
+			return isBoolean(e.Left) && isBoolean(e.Right)
+		}
+	case *ast.PrefixExpression:
+		return e.Token.Type == token.NOT && isBoolean(e.Right)
+	}
+	return false
+}
+
+// boolean returns the boolean expression e as a C truth value, 1 or 0.
+// The operands are all evaluated, as in BASIC.
+func (g *Generator) boolean(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		if op, ok := logicalOperators[e.Token.Type]; ok {
+			return "(" + g.boolean(e.Left) + " " + op + " " + g.boolean(e.Right) + ")"
+		}
+		op := relationalOperators[e.Token.Type]
+		if semantic.TypeOf(e.Left) == types.String {
+			return g.stringInfix(e, op)
+		}
+		return "(" + g.expression(e.Left) + " " + op + " " + g.expression(e.Right) + ")"
+	case *ast.PrefixExpression:
+		return "(!" + g.boolean(e.Right) + ")"
+	}
+	return g.expression(e)
+}
+
+// singleFuncs are the builtin functions whose single results are computed
+// as doubles, to be rounded to singles.
+var singleFuncs = map[string]bool{
+	"SQR": true, "SIN": true, "COS": true, "TAN": true, "ATN": true,
+	"LOG": true, "EXP": true, "VAL": true, "CSNG": true,
+}
+
+func (g *Generator) callExpression(e *ast.CallExpression) string {
+	if f := builtin.Lookup(e.Function.Value); f != nil {
+		call, ok := g.numericCall(f, e)
+		if !ok {
+			call = g.stringCall(f, e)
+		}
+		if singleFuncs[f.Name] && semantic.TypeOf(e) == types.Single {
+			return g.csng(call)
+		}
+		return call
+	}
+
+	if f, ok := g.table.Funcs[types.Canonical(e.Function.Value)]; ok {
+		args := []string{}
+		for i, a := range e.Arguments {
+			args = append(args, g.convert(a, semantic.TypeOf(f.Parameters[i])))
+		}
+		return funcName(f.Name.Value) + "(" + strings.Join(args, ", ") + ")"
+	}
+
+	g.errorf(e, "undefined function %s", e.Function.Value)
+	return "0"
+}
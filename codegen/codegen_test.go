@@ -0,0 +1,1124 @@
+package codegen
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/lexer"
+	"github.com/ysh86/b2c/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	l := lexer.New(strings.NewReader(input))
+	p := parser.New(l)
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("parser error: %v", err)
+	}
+
+	return program
+}
+
+func generate(t *testing.T, input string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := New().GenerateFragment(&out, parse(t, input)); err != nil {
+		t.Fatalf("codegen error: %v", err)
+	}
+
+	return out.String()
+}
+
+// compileAndRun builds the translation unit of input with cc and returns
+// the output of the program. The test is skipped if cc is not available.
+func compileAndRun(t *testing.T, input string, stdin string) string {
+	t.Helper()
+
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("cc not found")
+	}
+
+	var src bytes.Buffer
+	if err := New().Generate(&src, parse(t, input)); err != nil {
+		t.Fatalf("codegen error: %v", err)
+	}
+
+	dir := t.TempDir()
+	cfile := filepath.Join(dir, "prog.c")
+	exe := filepath.Join(dir, "prog")
+	if err := os.WriteFile(cfile, src.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(cc, "-std=c99", "-o", exe, cfile, "-lm").CombinedOutput()
+	if err != nil {
+		t.Fatalf("cc failed: %v\n%s\n%s", err, out, src.String())
+	}
+
+	cmd := exec.Command(exe)
+	cmd.Dir = dir // for the files of the program
+	cmd.Stdin = strings.NewReader(stdin)
+	out, _ = cmd.CombinedOutput()
+
+	return string(out)
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"10 A=B+1",
+			"N_10: b2c_line = 10;\nvf_A = b2c_csng((vf_B + 1));\n",
+		},
+		{
+			"10 IF A>1 THEN B=1:GOTO 10 ELSE *L",
+			"N_10: b2c_line = 10;\nif ((vf_A > 1)) {\n    vf_B = 1;\n    goto N_10;\n} else {\n    goto L_L;\n}\n",
+		},
+		{
+			"10 IF A<=B AND NOT C THEN X=A>B",
+			"N_10: b2c_line = 10;\nif (((-(vf_A <= vf_B)) & (~b2c_cint(vf_C)))) {\n    vf_X = (-(vf_A > vf_B));\n}\n",
+		},
+		{
+			"10 IF A THEN\n20 B=1\n30 ELSEIF B THEN\n40 ELSE B=2:END IF",
+			"N_10: b2c_line = 10;\nif (vf_A) {\n    N_20: b2c_line = 20;\n    vf_B = 1;\n    N_30: b2c_line = 30;\n" +
+				"} else if (vf_B) {\n    N_40: b2c_line = 40;\n} else {\n    vf_B = 2;\n}\n",
+		},
+		{
+			"10 ON N GOTO 10,*L",
+			"N_10: b2c_line = 10;\nswitch (b2c_cint(vf_N)) {\ncase 1:\n    goto N_10;\n    break;\ncase 2:\n    goto L_L;\n    break;\ndefault:\n    // nothing to do\n    break;\n}\n",
+		},
+		{
+			"10 A=1\n*L:B=2",
+			"N_10: b2c_line = 10;\nvf_A = 1;\n\n// -----------------------------------\nL_L:;\nvf_B = 2;\n",
+		},
+		{
+			"10 ON ERROR GOTO *H:ERROR 5:RESUME NEXT",
+			"N_10: b2c_line = 10;\nb2c_on_error_goto(1);\nb2c_raise(5);\nb2c_resume();\ngoto b2c_resume_next;\n",
+		},
+		{
+			"10 GOSUB 10:RETURN",
+			"N_10: b2c_line = 10;\nb2c_gosub_push(1);\ngoto N_10;\nb2c_ret_1:;\ngoto b2c_return;\n",
+		},
+		{
+			"10 FOR I=1 TO 3:FOR J=1 TO 2:X=I*J:NEXT:NEXT",
+			"N_10: b2c_line = 10;\nvf_I = 1;\nb2c_for1_end = 3;\nfor (; vf_I <= b2c_for1_end; vf_I += 1) {\n" +
+				"    vf_J = 1;\n    b2c_for2_end = 2;\n    for (; vf_J <= b2c_for2_end; vf_J += 1) {\n" +
+				"        vf_X = b2c_csng((vf_I * vf_J));\n    }\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestTranslationUnit(t *testing.T) {
+	input := `10 DIM C(2):A=1
+20 FOR I=0 TO 2:C(I)=A*I:NEXT
+30 IF C(2)>1 THEN GOSUB *SUB
+40 ON A GOTO 60,70
+50 *SUB:B=2:RETURN
+60 A=B+C(1)
+70 '
+`
+
+	compileAndRun(t, input, "")
+}
+
+func TestGosubReturn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			// nested GOSUB must return to the right place
+			"10 GOSUB 100:A=A+1:GOSUB 200:A=A+10\n20 IF A<>26 THEN RETURN\n30 GOTO 999\n" +
+				"100 GOSUB 200:A=A*2:RETURN\n200 A=A+5:RETURN\n999 '",
+			"",
+		},
+		{"10 RETURN", "RETURN without GOSUB in 10\n"},
+		{"10 GOSUB 10", "Out of memory in 10\n"},
+		{"10 ON 2 GOSUB 20,30:RETURN\n20 RETURN\n30 GOTO 20", "RETURN without GOSUB in 10\n"},
+	}
+
+	for _, tt := range tests {
+		actual := compileAndRun(t, tt.input, "")
+		if actual != tt.expected {
+			t.Errorf("input=%q: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestMangling(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 A=1:A!=2", "N_10: b2c_line = 10;\nvf_A = 1;\nvf_A = 2;\n"},
+		{"10 INT=1:ABORT%=2:LOG$=\"X\"", "N_10: b2c_line = 10;\nvf_INT = 1;\nvi_ABORT = 2;\nb2c_str_assign(&vs_LOG, b2c_lit(\"X\", 1));\n"},
+		{"10 DIM A(1):A(0)=A", "N_10: b2c_line = 10;\n// DIM A(1)\naf_A[0] = vf_A;\n"},
+		{"10 GOTO *main:GOSUB 10", "N_10: b2c_line = 10;\ngoto L_main;\nb2c_gosub_push(1);\ngoto N_10;\nb2c_ret_1:;\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	// C keywords and libc names must compile
+	compileAndRun(t, "10 DIM INT(2),CHAR$(1):INT=1:CHAR=2:ABORT=3:INT(1)=INT:CHAR$(0)=\"X\":CHAR$=CHAR$(0)\n20 *int:*exit:GOTO *return\n30 *return", "")
+}
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 A=1.5E-3+1D2", "N_10: b2c_line = 10;\nvf_A = b2c_csng((0.0015f + 100.0));\n"},
+		{"10 A#=.5*2", "N_10: b2c_line = 10;\nvd_A = b2c_csng((0.5 * 2));\n"},
+		{"10 A=1/2", "N_10: b2c_line = 10;\nvf_A = b2c_csng(((double)1 / 2));\n"},
+		{"10 A%=2.5", "N_10: b2c_line = 10;\nvi_A = b2c_cint(2.5);\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	// RETURN without GOSUB is used as an assertion
+	input := `10 A=1/2:IF A<>0.5 THEN RETURN
+20 A%=2.5:IF A%<>3 THEN RETURN
+30 A%=-2.5:IF A%<>-3 THEN RETURN
+40 A#=1D-3*1000:IF A#<>1 THEN RETURN
+50 A%=32767.6
+`
+	actual := compileAndRun(t, input, "")
+	if actual != "Overflow in 50\n" {
+		t.Errorf("expected=%q, got=%q", "Overflow in 50\n", actual)
+	}
+
+	// a single is checked where it is computed or stored
+	input = `10 ON ERROR GOTO 100
+20 PRINT 1E+38*10
+30 A=1E+38:A=A*2
+40 A#=A:A#=A#*4:PRINT A#:A#=A*4
+50 B=A#:PRINT A
+55 READ C:DATA 1E+39
+60 END
+100 PRINT "ERR";ERR;"IN";ERL:RESUME NEXT
+`
+	expected := `ERR 6 IN 20 
+ERR 6 IN 30 
+ 3.999999872114277D+38 
+ERR 6 IN 40 
+ERR 6 IN 50 
+ 1E+38 
+ERR 6 IN 55 
+`
+	actual = compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	// a single has the precision of a single, also as a double
+	input = `10 PRINT CDBL(1/3);1/3#
+20 A=.1:B#=A:PRINT B#;.1#
+30 B#=.1:PRINT B#;CDBL(.1)
+40 A=0:FOR I=1 TO 10:A=A+.1:NEXT:PRINT A;CDBL(A);A=1
+50 PRINT CDBL(SQR(2));CDBL(CSNG(1/3#));CDBL(VAL("0.1"))
+60 DEF FNH(X)=X/2:PRINT CDBL(FNH(1/3#))
+`
+	expected = ` .3333333432674408  .3333333333333333 
+ .1000000014901161  .1 
+ .1000000014901161  .1000000014901161 
+ 1  1.00000011920929  0 
+ 1.414213538169861  .3333333432674408  .1000000014901161 
+ .1666666716337204 
+`
+	actual = compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestForNext(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 FOR I%=10 TO 1 STEP -2:A=I%:NEXT",
+			"N_10: b2c_line = 10;\nvi_I = 10;\nb2c_for1_end = 1;\nfor (; vi_I >= b2c_for1_end; vi_I += (-(2))) {\n" +
+				"    vf_A = vi_I;\n}\n"},
+		{"10 FOR I=1 TO N STEP S:A=I:NEXT",
+			"N_10: b2c_line = 10;\nvf_I = 1;\nb2c_for1_end = vf_N;\nb2c_for1_step = vf_S;\n" +
+				"for (; b2c_for1_step >= 0 ? vf_I <= b2c_for1_end : vf_I >= b2c_for1_end; vf_I += b2c_for1_step) {\n" +
+				"    vf_A = vf_I;\n}\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	// RETURN without GOSUB is used as an assertion
+	input := `10 N=0:FOR I=1 TO 10 STEP 2:N=N+1:NEXT:IF N<>5 OR I<>11 THEN RETURN
+20 N=0:FOR I%=10 TO 1 STEP -3:N=N+I%:NEXT:IF N<>22 OR I%<>-2 THEN RETURN
+30 N=0:FOR B=1.0 TO 0.0 STEP -0.25:N=N+1:NEXT:IF N<>5 THEN RETURN
+40 N=0:FOR I=5 TO 1:N=N+1:NEXT:IF N<>0 OR I<>5 THEN RETURN
+50 E=3:S=1:N=0:FOR I=1 TO E STEP S:E=10:S=5:N=N+1:NEXT:IF N<>3 THEN RETURN
+60 S=-1:N=0:FOR I=3 TO 1 STEP S:N=N+1:NEXT:IF N<>3 OR I<>0 THEN RETURN
+70 RETURN
+`
+	actual := compileAndRun(t, input, "")
+	if actual != "RETURN without GOSUB in 70\n" {
+		t.Errorf("expected=%q, got=%q", "RETURN without GOSUB in 70\n", actual)
+	}
+}
+
+func TestNext(t *testing.T) {
+	// RETURN without GOSUB is used as an assertion
+	input := `10 N=0:FOR I=1 TO 3:FOR J=1 TO 2:N=N+1:NEXT J,I:IF N<>6 THEN RETURN
+20 N=0:FOR I%=1 TO 4:NEXT I%:IF I%<>5 THEN RETURN
+30 N=0
+40 FOR I=1 TO 10
+50 IF I>3 THEN 70
+60 N=N+I
+70 NEXT I
+80 IF N<>6 OR I<>11 THEN RETURN
+90 FOR I!=1 TO 2:FOR J=1 TO 2
+100 N=N+1
+110 NEXT J, I
+120 IF N<>10 THEN RETURN
+130 N=0:FOR I=1 TO 5:N=N+I:IF N<6 THEN NEXT:RETURN
+140 IF I<>3 THEN RETURN
+150 FOR I=1 TO 3:IF I<5 THEN NEXT I:N=-1 ELSE RETURN
+160 IF N<>-1 OR I<>4 THEN RETURN
+170 N=0:FOR I=1 TO 3
+180 N=N+1
+190 IF N<10 THEN NEXT ELSE RETURN
+200 IF I<>4 OR N<>3 THEN RETURN
+210 FOR I=1 TO 0:IF 1 THEN NEXT:N=5
+220 IF N<>5 THEN RETURN
+230 FOR I=1 TO 9:IF I=4 THEN 250 ELSE NEXT
+240 RETURN
+250 IF I<>4 THEN RETURN
+260 RETURN
+`
+	actual := compileAndRun(t, input, "")
+	if actual != "RETURN without GOSUB in 260\n" {
+		t.Errorf("expected=%q, got=%q", "RETURN without GOSUB in 260\n", actual)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"10 NEXT", "1:4: NEXT without FOR"},
+		{"10 FOR I=1 TO 2:A=I", "1:4: FOR without NEXT"},
+		{"10 FOR I=1 TO 2:NEXT J", "1:22: NEXT J does not match FOR I"},
+		{"10 FOR I=1 TO 2:FOR J=1 TO 2:NEXT I,J", "1:35: NEXT I does not match FOR J"},
+		{"10 FOR I=1 TO 2:NEXT I,J", "1:23: NEXT without FOR"},
+		{"10 FOR I=1 TO 2:IF I THEN NEXT J", "1:32: NEXT J does not match FOR I"},
+		{"10 FOR I=1 TO 2:IF I THEN NEXT:NEXT", "1:32: FOR has more than one NEXT in IF"},
+		{"10 FOR I=1 TO 2:FOR J=1 TO 2:IF I THEN NEXT J,I", "1:46: NEXT in IF cannot end more than one FOR"},
+		{"10 FOR I=1 TO 2:WHILE 1:IF I THEN NEXT", "1:35: NEXT without FOR"},
+		{"10 FOR I=1 TO 2\n20 IF I THEN\n30 NEXT\n40 END IF", "3:4: NEXT without FOR"},
+	}
+
+	for _, tt := range errors {
+		l := lexer.New(strings.NewReader(tt.input))
+		_, err := parser.New(l).ParseProgram()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	input := `10 I=0
+20 WHILE I<3
+30   PRINT I;
+40   I=I+1
+50 WEND:PRINT
+60 DO WHILE I>0:I=I-1:PRINT I;:LOOP:PRINT
+70 DO UNTIL I=3:I=I+1:LOOP:PRINT I
+80 DO:I=I+1:LOOP WHILE I<5:PRINT I
+90 DO
+100  I=I+1
+110  FOR J=1 TO 10
+120    IF I>7 THEN EXIT DO
+130  NEXT
+140 LOOP UNTIL I>100
+150 PRINT I;J
+160 DO:DO:EXIT DO:LOOP:K=K+1:IF K=2 THEN EXIT DO
+170 LOOP:PRINT K
+180 WHILE 0:WEND:DO:LOOP UNTIL 1:PRINT "END"
+`
+	expected := ` 0  1  2 
+ 2  1  0 
+ 3 
+ 5 
+ 8  1 
+ 2 
+END
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"10 WEND", "1:4: WEND without WHILE"},
+		{"10 WHILE 1:A=1", "1:4: WHILE without WEND"},
+		{"10 LOOP", "1:4: LOOP without DO"},
+		{"10 DO:A=1\n20 B=1", "1:4: DO without LOOP"},
+		{"10 EXIT DO", "1:4: EXIT DO outside DO...LOOP"},
+		{"10 DO WHILE 1:LOOP UNTIL 1", "1:20: DO and LOOP cannot both have a condition"},
+		{"10 WHILE 1 A=1:WEND", "1:12: unexpected A after WHILE"},
+	}
+
+	for _, tt := range errors {
+		l := lexer.New(strings.NewReader(tt.input))
+		_, err := parser.New(l).ParseProgram()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestBlockIf(t *testing.T) {
+	input := `10 FOR I=1 TO 4
+20 IF I=1 THEN
+30   PRINT "ONE";
+40 ELSEIF I=2 THEN
+50   PRINT "TWO";
+60   IF I>1 THEN PRINT "!"; ELSE PRINT "?";
+70 ELSEIF I=3 THEN
+80 ELSE
+90   IF I=4 THEN
+100    PRINT "FOUR";
+110  END IF
+120 END IF
+130 NEXT:PRINT
+140 IF 1 THEN
+150 ELSE
+160 PRINT "NO"
+170 END IF:PRINT "DONE"
+`
+	expected := `ONETWO!FOUR
+DONE
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"10 IF A THEN\n20 A=1", "1:4: IF without END IF"},
+		{"10 IF A THEN\n20 ELSEIF B THEN\n30 A=1", "2:4: ELSEIF without END IF"},
+		{"10 IF A THEN\n20 ELSE\n30 ELSE\n40 END IF", "3:4: unexpected ELSE in block IF"},
+		{"10 END IF", "1:4: END IF without IF"},
+	}
+
+	for _, tt := range errors {
+		l := lexer.New(strings.NewReader(tt.input))
+		_, err := parser.New(l).ParseProgram()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestIfGoto(t *testing.T) {
+	input := `10 A=1
+20 IF A GOTO 40
+30 PRINT "BAD"
+40 IF A=2 GOTO *L ELSE 60
+50 PRINT "BAD"
+60 IF A THEN 70 ELSE 50
+70 IF A=1 THEN ELSE PRINT "BAD"
+80 IF A=0 THEN ELSE PRINT "ELSE"
+90 IF A=0 THEN 50 ELSE *L
+*L:PRINT "L"
+100 IF A=1 GOTO 110 ELSE PRINT "BAD"
+110 IF A THEN PRINT "END" ELSE
+`
+	expected := `ELSE
+L
+END
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestDefFn(t *testing.T) {
+	input := `10 X=100:Y$="!"
+20 DEF FNA(X)=X*X+1
+30 DEF FNB$(A$,N%)=LEFT$(A$,N%)+Y$
+40 DEF FN C=X/4
+50 DEF FNI%(X)=X/2
+60 PRINT FNA(3);X;FNB$("HELLO",2.6);FNC;FN A(FNA(1));FNI%(3)
+70 GOTO 90
+80 PRINT FNR(2):END
+90 DEF FNR(N)=FNA(N)+FNZ
+100 DEF FNZ=7:GOTO 80
+`
+	expected := ` 10  100 HEL! 25  5  2 
+ 12 
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	// a function called before its DEF has run is undefined
+	input = `10 ON ERROR GOTO 100
+20 PRINT FNA(1)
+30 DEF FNA(X)=X+FNB
+40 PRINT FNA(1)
+50 DEF FNB=1
+60 PRINT FNA(1)
+70 END
+100 PRINT "ERR";ERR;"IN";ERL:RESUME NEXT
+`
+	expected = `ERR 18 IN 20 
+ERR 18 IN 40 
+ 2 
+`
+	actual = compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"10 DEF A(X)=X", "1:8: expected a function name FNx, got A instead"},
+		{"10 DEF FNA(1)=X", "1:12: expected next token to be IDENT, got NUM instead"},
+		{"10 DEF FNA(X)", "1:14: expected next token to be =, got EOF instead"},
+	}
+
+	for _, tt := range errors {
+		l := lexer.New(strings.NewReader(tt.input))
+		_, err := parser.New(l).ParseProgram()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestErrorTrapping(t *testing.T) {
+	input := `10 ON ERROR GOTO *H
+20 DIM A(3)
+30 B=0:X=1/B:PRINT "AFTER";X
+40 I=5:A(I)=1:PRINT "NEXT"
+50 Y$=LEFT$("AB",-1)
+60 ERROR 200
+70 FOR I=1 TO 3:IF I=2 THEN ERROR 13
+80 PRINT "I";I:NEXT
+90 N=0
+100 PRINT 10/N
+110 ON ERROR GOTO 0
+120 PRINT "DONE"
+130 ERROR 11
+*H:PRINT "ERR";ERR;"ERL";ERL
+150 IF ERR=11 AND N=0 AND ERL=100 THEN N=2:RESUME
+160 IF ERR=11 THEN B=4:RESUME
+170 RESUME NEXT
+`
+	expected := `ERR 11 ERL 30 
+AFTER .25 
+ERR 9 ERL 40 
+NEXT
+ERR 5 ERL 50 
+ERR 200 ERL 60 
+I 1 
+ERR 13 ERL 70 
+I 2 
+I 3 
+ERR 11 ERL 100 
+ 5 
+DONE
+Division by zero in 130
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 DIM A(2):I=3\n20 A(I)=1", "Subscript out of range in 20\n"},
+		{"10 ERROR 300", "Illegal function call in 10\n"},
+		{"10 ERROR 21", "Unprintable error in 10\n"},
+		{"10 RESUME", "RESUME without error in 10\n"},
+		{"10 ON ERROR GOTO 30\n20 ERROR 4\n30 PRINT 1/0", "Division by zero in 30\n"},
+		{"10 ON ERROR GOTO 30\n20 ERROR 4\n30 PRINT ERR", " 4 \nNo RESUME in 30\n"},
+	}
+
+	for _, tt := range tests {
+		actual := compileAndRun(t, tt.input, "")
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestVariableStatements(t *testing.T) {
+	input := `10 DIM A(2),B$(1,1),C%(3)
+20 A=1:A$="X":A(1)=5:B$(1,1)="Y":C%(2)=7:I%=3
+30 SWAP A,A(1):SWAP A$,B$(1,1)
+40 PRINT A;A(1);A$;B$(1,1)
+50 ERASE A,B$
+60 PRINT A(1);"[";B$(1,1);"]";C%(2)
+70 CLEAR ,&H8000
+80 PRINT A;"[";A$;"]";C%(2);I%
+90 RANDOMIZE 1:IF A=0 THEN END
+100 PRINT "BAD"
+`
+	expected := ` 5  1 YX
+ 0 [] 7 
+ 0 [] 0  0 
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 PRINT 1\n20 STOP\n30 PRINT 2", " 1 \nBreak in 20\n"},
+		{"10 GOSUB *S:PRINT 2\n20 END\n*S:PRINT 1:END", " 1 \n"},
+	}
+
+	for _, tt := range tests {
+		actual := compileAndRun(t, tt.input, "")
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`10 PRINT "A";B%,`,
+			"N_10: b2c_line = 10;\nb2c_print_str(b2c_lit(\"A\", 1));\nb2c_print_int(vi_B);\nb2c_print_zone();\n"},
+		{`10 ? TAB(3)A#`,
+			"N_10: b2c_line = 10;\nb2c_print_tab(3);\nb2c_print_dbl(vd_A);\nb2c_print_newline();\n"},
+		{`10 PRINT "A" "B" A B$`,
+			"N_10: b2c_line = 10;\nb2c_print_str(b2c_lit(\"A\", 1));\nb2c_print_str(b2c_lit(\"B\", 1));\nb2c_print_sng(vf_A);\nb2c_print_str(vs_B);\nb2c_print_newline();\n"},
+		{`10 PRINT USING "##.#";A;`,
+			"N_10: b2c_line = 10;\nb2c_using_begin(b2c_lit(\"##.#\", 4));\nb2c_using_num(vf_A, 7);\nb2c_using_end(0);\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	input := `10 A=1.5:B%=-3:C#=1D20:S$="HI"
+20 PRINT A;B%;C#;S$
+30 PRINT "X","Y";:PRINT "Z"
+40 PRINT TAB(5);"T";SPC(3);"S":PRINT "LONG";TAB(2);"L"
+50 ? 1/3, -.5, 1E7, .0001
+60 PRINT 100000*10;1.5E-8;123456.7;-0
+70 PRINT USING "##.## [&] **##.# $$##.## +#.##^^^^ !";3.14159;"AB";3.5;2.5;-1234.5;"QQ"
+80 PRINT USING "###,###.## \  \";1234.567;"ABCDEF"
+90 PRINT USING "#";-5;12:PRINT USING "##.##-";-1;1
+100 PRINT
+110 PRINT "END";
+`
+	expected := ` 1.5 -3  1D+20 HI
+X             YZ
+    T   S
+LONG
+ L
+ .3333333     -.5            1E+07         .0001 
+ 1000000  1.5E-08  123456.7  0 
+ 3.14 [AB] ***3.5   $2.50 -1.23E+03 Q
+  1,234.57 ABCD
+%-5%12
+ 1.00- 1.00 
+
+END`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	// the numbers too large for a field, and a field of too many digits
+	input = `10 ON ERROR GOTO 100
+20 A#=1D300:PRINT USING "##.##";A#;-1E30
+30 PRINT USING "#.#######################";1/4
+40 PRINT USING "$$#,###,###.##";1D21
+50 PRINT USING "#########################";1
+60 END
+100 PRINT "ERR";ERR;"IN";ERL:RESUME NEXT
+`
+	expected = `%1D+300%-1E+30
+0.25000000000000000000000
+%$1,000,000,000,000,000,000,000.00
+ERR 5 IN 50 
+`
+	actual = compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`10 INPUT "N", A%, B$`,
+			"N_10: b2c_line = 10;\nb2c_input(\"N\", \"ns\");\nvi_A = b2c_cint(b2c_input_num(0));\nb2c_str_assign(&vs_B, b2c_input_str(1));\n"},
+		{`10 LINE INPUT A$`,
+			"N_10: b2c_line = 10;\nb2c_str_assign(&vs_A, b2c_line_input(\"\"));\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	input := `10 DIM B(3)
+20 INPUT "NAME"; N$
+30 INPUT "X,Y", X, B(2)
+40 INPUT A%
+50 LINE INPUT "LINE> "; L$
+60 PRINT N$;X;B(2);A%;L$
+70 INPUT A
+`
+	stdin := "BOB\n1\n1,2,3\n\"A\",2\n1.5 , 2E1\nabc\n2.6\n  hello, \"world\"  \n"
+	expected := "NAME? X,Y?Redo from start\nX,Y?Redo from start\nX,Y?Redo from start\n" +
+		"X,Y? ?Redo from start\n? LINE> BOB 1.5  20  3   hello, \"world\"  \n? "
+	actual := compileAndRun(t, input, stdin)
+	if actual != expected+"Input past end in 70\n" {
+		t.Errorf("expected=%q\ngot=%q", expected+"Input past end in 70\n", actual)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`10 PRINT #1, A;`,
+			"N_10: b2c_line = 10;\nb2c_print_file(1);\nb2c_print_sng(vf_A);\nb2c_print_screen();\n"},
+		{`10 INPUT #N, A$, I%`,
+			"N_10: b2c_line = 10;\nb2c_input_file(b2c_cint(vf_N));\nb2c_str_assign(&vs_A, b2c_input_file_str());\nvi_I = b2c_cint(b2c_input_file_num());\n"},
+		{`10 OPEN "O", 2, F$, 128: CLOSE`,
+			"N_10: b2c_line = 10;\nb2c_open(b2c_lit(\"O\", 1), 2, vs_F, 128);\nb2c_close_all();\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	input := `10 OPEN "T.DAT" FOR OUTPUT AS #1
+20 PRINT #1, "HELLO";12;-3.5:PRINT #1, "Z";TAB(5);"Q"
+30 WRITE #1, "A,B", 1.5, -2, "X"
+40 CLOSE #1
+50 OPEN "A", #2, "T.DAT":PRINT #2, USING "##.#";3.14159:CLOSE
+60 OPEN "T.DAT" FOR INPUT AS #1
+70 LINE INPUT #1, L$:PRINT "[";L$;"]";LOF(1)
+80 LINE INPUT #1, L$:INPUT #1, A$, X, I%, B$
+90 PRINT "[";A$;"]";X;I%;"[";B$;"]"
+100 WHILE NOT EOF(1):INPUT #1, X:PRINT X:WEND
+110 CLOSE 1:NAME "T.DAT" AS "U.DAT"
+120 ON ERROR GOTO *H
+130 OPEN "I",#3,"T.DAT"
+140 KILL "U.DAT":KILL "U.DAT"
+150 OPEN "U.DAT" FOR OUTPUT AS #1:PRINT #1, 1/0:OPEN "U.DAT" FOR INPUT AS #2
+160 INPUT #1, A
+170 WRITE:WRITE 1,"A"
+180 END
+*H:PRINT "ERR";ERR;"ERL";ERL:RESUME NEXT
+`
+	expected := `[HELLO 12 -3.5 ] 43 
+[A,B] 1.5 -2 [X]
+ 3.1 
+ERR 53 ERL 130 
+ERR 53 ERL 140 
+ERR 11 ERL 150 
+ERR 55 ERL 150 
+ERR 54 ERL 160 
+
+1,"A"
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	tests = []struct {
+		input    string
+		expected string
+	}{
+		{"10 OPEN \"T\" FOR OUTPUT AS #1:CLOSE\n20 OPEN \"T\" FOR INPUT AS #1\n30 INPUT #1, A", "Input past end in 30\n"},
+		{"10 PRINT #16, 1", "Bad file number in 10\n"},
+		{"10 OPEN \"\" FOR OUTPUT AS #1", "Bad file name in 10\n"},
+	}
+
+	for _, tt := range tests {
+		actual := compileAndRun(t, tt.input, "")
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestRandomFiles(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`10 FIELD #1, 2 AS A$: RSET A$=MKI$(I)`,
+			"N_10: b2c_line = 10;\nb2c_field_begin(1);\nb2c_field(&vs_A, 2);\nb2c_rset(&vs_A, b2c_mki(b2c_cint(vf_I)));\n"},
+		{`10 GET #1: PUT 1, R`,
+			"N_10: b2c_line = 10;\nb2c_get(1, 0);\nb2c_put(1, b2c_record(vf_R));\n"},
+	}
+
+	for _, tt := range tests {
+		actual := generate(t, tt.input)
+		if actual != tt.expected {
+			t.Errorf("input=%q\nexpected=%q\ngot=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	input := `10 FOR I=1 TO 5:READ X:T$=MKS$(X)
+20 FOR J=1 TO 4:PRINT RIGHT$("0"+HEX$(ASC(MID$(T$,J,1))),2);:NEXT:PRINT CVS(T$)
+30 NEXT
+40 DATA 1,-1,10,.1,0
+50 S$=MKD$(.5#):PRINT LEN(S$);ASC(MID$(S$,8,1));CVD(S$);CVI(MKI$(-2))
+60 OPEN "R.DAT" AS #1 LEN=16
+70 FIELD #1, 10 AS N$, 2 AS A$, 4 AS B$
+80 FOR R=1 TO 3:LSET N$="NAME"+STR$(R):RSET A$=MKI$(R*100):LSET B$=MKS$(R/4):PUT #1, R:NEXT
+90 PRINT LOF(1):CLOSE
+100 OPEN "R",#2,"R.DAT",16:FIELD 2, 10 AS N$, 2 AS A$, 4 AS B$:FIELD 2, 16 AS R$
+110 GET #2, 2:PRINT "[";N$;"]";CVI(A$);CVS(B$)
+120 LSET N$="X":PRINT "[";LEFT$(R$,10);"]"
+130 GET #2:PRINT "[";N$;"]";CVI(A$);CVS(B$);EOF(2)
+140 GET #2:PRINT EOF(2)
+145 N$="MINE":GET #2, 1:LSET N$="AB":PRINT "[";N$;"]";"[";LEFT$(R$,6);"]"
+150 X$="ABC":RSET X$="Z":PRINT "[";X$;"]":LSET X$="LONGER":PRINT "[";X$;"]"
+160 ON ERROR GOTO *H
+170 FIELD #2, 20 AS X$
+180 GET #2, 0
+190 PRINT MKS$(1E38*10)
+200 X=CVS("AB")
+210 END
+*H:PRINT "ERR";ERR;"ERL";ERL:RESUME NEXT
+`
+	expected := `00000081 1 
+00008081-1 
+00002084 10 
+CDCC4C7D .1 
+00000000 0 
+ 8  128  .5 -2 
+ 48 
+[NAME 2    ] 200  .5 
+[X         ]
+[NAME 3    ] 300  .75  0 
+-1 
+[AB  ][NAME 1]
+[  Z]
+[LON]
+ERR 50 ERL 170 
+ERR 63 ERL 180 
+ERR 6 ERL 190 
+ERR 5 ERL 200 
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestData(t *testing.T) {
+	input := `10 DIM A(3)
+20 READ N, S$, A(1), I%
+30 PRINT N;S$;A(1);I%
+40 DATA 10, "HELLO, WORLD:X", 1.5E2
+50 DATA 2.5:REM
+60 RESTORE 50:READ X$:PRINT X$
+70 RESTORE:READ X:PRINT X
+80 RESTORE *L:READ X$,Y$,Z:PRINT X$;"/";Y$;"/";Z
+90 READ X
+*L:DATA  unquoted  text , ,
+`
+	expected := ` 10 HELLO, WORLD:X 150  3 
+2.5
+ 10 
+unquoted  text// 0 
+Out of DATA in 90
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	actual = compileAndRun(t, "10 READ A,B,C%,D,A$\n20 DATA &H10,&o17,&HFFFF,&17,&HFF\n30 PRINT A;B;C%;D;A$\n", "")
+	if actual != " 16  15 -1  15 &HFF\n" {
+		t.Errorf("expected=%q, got=%q", " 16  15 -1  15 &HFF\n", actual)
+	}
+
+	actual = compileAndRun(t, "10 READ A$,B\n20 DATA X,Y\n", "")
+	if actual != "Syntax error in 20\n" {
+		t.Errorf("expected=%q, got=%q", "Syntax error in 20\n", actual)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	input := `10 A$="HELLO":B$=A$+", "+"WORLD"
+20 PRINT B$;LEN(B$);ASC(A$);CHR$(65)
+30 PRINT MID$(B$,8);"|";MID$(B$,2,3);"|";LEFT$(B$,2);"|";RIGHT$(B$,3);"|";MID$(A$,9);"|"
+40 PRINT STR$(12);STR$(-1.5);"|";VAL(" 1 2.5E1X");VAL("ABC");VAL("-.5")
+50 PRINT INSTR(B$,"O");INSTR(6,B$,"O");INSTR(B$,"Z");INSTR(B$,"")
+60 PRINT STRING$(3,"*");STRING$(2,66);"[";SPACE$(2);"]";HEX$(255);" ";HEX$(-1);" ";OCT$(8)
+70 IF A$="HELLO" THEN PRINT "EQ"
+80 IF "ABC"<"ABD" AND "AB"<"ABC" AND "B">"ABC" AND A$<>B$ THEN PRINT "LT"
+90 A$=A$+A$:A$=MID$(A$,3):PRINT A$
+100 DIM C$(2):C$(1)="X":C$(1)=C$(1)+C$(1):PRINT C$(1);C$(0);"."
+110 FOR I=1 TO 300:S$=S$+"A":NEXT
+`
+	expected := `HELLO, WORLD 12  72 A
+WORLD|ELL|HE|RLD||
+ 12-1.5| 125  0 -.5 
+ 5  9  0  1 
+***BB[  ]FF FFFF 10
+EQ
+LT
+LLOHELLO
+XX.
+String too long in 110
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestMath(t *testing.T) {
+	input := `10 PRINT RND;RND(1);RND(0);RND
+20 PRINT ABS(-3);ABS(-2.5);INT(-2.5);INT(2.5);FIX(-2.5);SGN(-4);SGN(0);SGN(.1)
+30 PRINT SQR(16);SIN(0);COS(0);ATN(1)*4;LOG(1);EXP(1)
+40 D#=1:D#=D#/3:PRINT CINT(2.5);CINT(-2.5);CSNG(D#);CDBL(D#)
+50 I%=-5:PRINT ABS(I%)+1;INT(I%/2);FIX(I%/2)
+60 A=RND(-1):B=RND:C=RND(-1):D=RND
+70 IF A=C AND B=D THEN PRINT "SAME"
+80 RANDOMIZE 42:IF RND<>B THEN PRINT "SEEDED"
+90 PRINT LOG(0)
+`
+	expected := ` .1213501  .651861  .651861  .8688611 
+ 3  2.5 -3  2 -2 -1  0  1 
+ 4  0  1  3.141593  0  2.718282 
+ 3 -3  .3333333  .3333333333333333 
+ 6 -3 -2 
+SAME
+SEEDED
+Illegal function call in 90
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestBuiltinArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 A$=LEFT$(A$)", "1:7: wrong number of arguments to LEFT$"},
+		{"10 A=SQR(1,2)", "1:6: wrong number of arguments to SQR"},
+		{"10 A=RND(1,2)", "1:6: wrong number of arguments to RND"},
+		{"10 A=MID$(A$)", "1:6: wrong number of arguments to MID$"},
+		{"10 A=LEN(A$,B$)", "1:6: wrong number of arguments to LEN"},
+		{"10 A=ERR(1)", "1:6: wrong number of arguments to ERR"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(strings.NewReader(tt.input))
+		_, err := parser.New(l).ParseProgram()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `10 A=3:B=5
+20 PRINT A<B;A>B;A<=3;A=<2;B>=5;B=>6;A<>B;A><A
+30 PRINT NOT 0;NOT -1;NOT A=3;5 AND 3;5 OR 3;5 XOR 3;5 EQV 3;5 IMP 3;-1 IMP 0
+40 PRINT 7\2;-7\2;10.7\4;7 MOD 3;-7 MOD 3;10.4 MOD 4;2^10;-2^2;2^-1;2^3^2
+50 PRINT 1+2*3^2 MOD 5;12\4*3;NOT 1+1;3>2 AND 2>1;-(3>2)
+60 IF A<B AND NOT B<A THEN PRINT "OK"
+70 X=A>2 OR B>9:PRINT X;"X"<"Y";"X"+"Y"="XY"
+80 PRINT 1 MOD 0
+`
+	expected := `-1  0 -1  0 -1  0 -1  0 
+-1  0  0  1  7  6 -7 -5  0 
+ 3 -3  2  1 -1  2  1024 -4  .5  64 
+ 4  1 -3 -1  1 
+OK
+-1 -1 -1 
+Division by zero in 80
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	input := `10 ON ERROR GOTO 100
+20 A%=30000+30000
+30 A%=-32768:B%=A%-1
+40 C%=200*200
+50 PRINT ABS(A%)
+60 PRINT 32767+0;-32767-1;ABS(-32767);A%*1
+70 END
+100 PRINT "ERR";ERR;"IN";ERL:RESUME NEXT
+`
+	expected := `ERR 6 IN 20 
+ERR 6 IN 30 
+ERR 6 IN 40 
+ERR 6 IN 50 
+ 32767 -32768  32767 -32768 
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	input := `10 PRINT &H7F;&HFFFF;&hff;&O17;&17;&B1010;&H8000;-&H10
+20 PRINT 10%;3.5!;2.0#;1/3#;10#/3;123456789!;1.23456789#
+30 DIM B(&H3):B(&H3)=1:PRINT B(3);HEX$(&HFF00 AND &H0FF0)
+`
+	expected := ` 127 -1  255  15  15  10 -32768 -16 
+ 10  3.5  2  .3333333333333333  3.333333333333333  1.234568E+08  1.23456789 
+ 1 F00
+`
+	actual := compileAndRun(t, input, "")
+	if actual != expected {
+		t.Errorf("expected=%q\ngot=%q", expected, actual)
+	}
+
+	for _, input := range []string{"10 A=&H10000", "10 A=32768%", "10 A=1.5%"} {
+		l := lexer.New(strings.NewReader(input))
+		if _, err := parser.New(l).ParseProgram(); err == nil {
+			t.Errorf("input=%q: expected a parser error", input)
+		}
+	}
+}
+
+func TestUndefinedTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 GOTO 20", "1:9: undefined line number 20"},
+		{"10 GOSUB *L", "1:11: undefined label *L"},
+		{"10 ON A GOTO 10,*L,30", "1:18: undefined label *L"},
+		{"10 RESTORE 20", "1:12: undefined line number 20"},
+		{"10 ON ERROR GOTO *H", "1:19: undefined label *H"},
+		{"10 RESUME 30", "1:11: undefined line number 30"},
+	}
+
+	for _, tt := range tests {
+		err := New().Generate(&bytes.Buffer{}, parse(t, tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10 CLS", "1:4: unsupported statement: CLS"},
+		{"10 LOCATE 1,2", "1:4: unsupported statement: LOCATE"},
+		{"10 PRINT 1:LPRINT X", "1:12: unsupported statement: LPRINT"},
+		{"10 FOO(1)", "1:4: unsupported statement: FOO"},
+		{"10 PRINT FOO(1)", "1:10: undefined function FOO"},
+	}
+
+	for _, tt := range tests {
+		err := New().Generate(&bytes.Buffer{}, parse(t, tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	input := `10 DIM B%(3),C#(1,2),D$(4)
+20 A=1:A$="X":I%=B%(0):C#(1,2)=A:D$(1)=A$
+`
+	var out bytes.Buffer
+	if err := New().Generate(&out, parse(t, input)); err != nil {
+		t.Fatalf("codegen error: %v", err)
+	}
+
+	expected := `static float vf_A;
+static b2c_str vs_A;
+static int vi_I;
+static int ai_B[4];
+static double ad_C[2][3];
+static b2c_str as_D[5];
+`
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("globals wrong. expected=%q, got=%q", expected, out.String())
+	}
+
+	compileAndRun(t, input, "")
+}
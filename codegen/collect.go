@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/types"
+)
+
+// collect records the jump targets and the DATA items of program, and
+// whether it traps errors.
+func (g *Generator) collect(program *ast.Program) {
+	line := "0"
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LineNoStatement:
+			g.targets[labelName(n.Name)] = true
+			g.dataIndex[labelName(n.Name)] = len(g.data)
+			line = n.Name.Value
+		case *ast.LabelStatement:
+			g.targets[labelName(n.Name)] = true
+			g.dataIndex[labelName(n.Name)] = len(g.data)
+		case *ast.DataStatement:
+			for _, item := range n.Items {
+				g.data = append(g.data, datum{item: item, line: line})
+			}
+		case *ast.OnErrorStatement:
+			g.trapping = true
+		case ast.Expression:
+			return false
+		}
+		return true
+	})
+}
+
+// checkTargets reports GOTO/GOSUB/RESTORE/RESUME destinations that are
+// never defined.
+func (g *Generator) checkTargets(program *ast.Program) {
+	check := func(name *ast.Identifier) {
+		if g.targets[labelName(name)] {
+			return
+		}
+		if name.IsLineNo() {
+			g.errorf(name, "undefined line number %s", name.Value)
+		} else {
+			g.errorf(name, "undefined label *%s", name.Value)
+		}
+	}
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GotoStatement:
+			check(n.Name)
+		case *ast.GosubStatement:
+			check(n.Name)
+		case *ast.RestoreStatement:
+			if n.Name != nil {
+				check(n.Name)
+			}
+		case *ast.OnStatement:
+			for _, name := range n.Names {
+				check(name)
+			}
+		case *ast.OnErrorStatement:
+			if n.Name != nil {
+				check(n.Name)
+			}
+		case *ast.ResumeStatement:
+			if n.Name != nil {
+				check(n.Name)
+			}
+		}
+		return true
+	})
+}
+
+// globals writes the declarations hoisted out of main().
+func (g *Generator) globals(out *emitter) {
+	for _, sym := range g.table.ScalarList() {
+		out.line("static %s;", cDecl(sym.Type, scalarName(sym.Name)))
+	}
+
+	for _, sym := range g.table.ArrayList() {
+		var dims strings.Builder
+		for _, d := range sym.Dims { // TODO: x,y が逆かも
+			dims.WriteString("[" + strconv.FormatInt(d+1, 10) + "]")
+		}
+		out.line("static %s;", cDecl(sym.Type, arrayName(sym.Name)+dims.String()))
+	}
+
+	for _, t := range g.temps {
+		out.line("%s", t)
+	}
+
+	out.blank()
+}
+
+// cType returns the C type of a BASIC type. Single precision values are
+// computed in double as well; the precision only matters when printing.
+func cType(t types.Type) string {
+	switch t {
+	case types.Integer:
+		return "int"
+	case types.Single:
+		return "float"
+	case types.Double:
+		return "double"
+	case types.String:
+		return "b2c_str"
+	}
+	return "int"
+}
+
+// cDecl returns the C declaration of name with the BASIC type t.
+func cDecl(t types.Type, name string) string {
+	return cType(t) + " " + name
+}
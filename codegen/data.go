@@ -0,0 +1,142 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/types"
+)
+
+// datum is an item of DATA and the line it is on.
+type datum struct {
+	item ast.DataItem
+	line string
+}
+
+func (g *Generator) readStatement(s *ast.ReadStatement) {
+	g.require("read")
+
+	for _, n := range s.Names {
+		switch semantic.TypeOf(n) {
+		case types.String:
+			g.assign(n, "b2c_read_str()")
+		case types.Integer:
+			g.require("cint")
+			g.assign(n, "b2c_cint(b2c_read_num())")
+		case types.Single:
+			g.assign(n, g.csng("b2c_read_num()"))
+		default:
+			g.assign(n, "b2c_read_num()")
+		}
+	}
+}
+
+func (g *Generator) restoreStatement(s *ast.RestoreStatement) {
+	g.require("data")
+
+	if s.Name == nil {
+		g.e.line("b2c_data_ptr = 0;")
+		return
+	}
+	g.e.line("b2c_data_ptr = %d; // %s", g.dataIndex[labelName(s.Name)], s.String())
+}
+
+// dataTable writes the items of all DATA statements in the order of the
+// program. An item has both its text and, if it is a number, its value.
+func (g *Generator) dataTable(out *emitter) {
+	out.line("static const struct b2c_datum b2c_data[] = {")
+	out.in()
+	for _, d := range g.data {
+		v, ok := dataNumber(d.item)
+		isnum := 0
+		if ok {
+			isnum = 1
+		}
+		out.line("{%s, %s, %d, %s},", cString(d.item.Value), cFloat(v), isnum, d.line)
+	}
+	out.line("{NULL, 0.0, 0, 0} /* the end */")
+	out.out()
+	out.line("};")
+}
+
+// dataNumber returns the value of a DATA item READ into a numeric variable.
+// An empty item is 0.
+func dataNumber(item ast.DataItem) (float64, bool) {
+	if item.Quoted {
+		return 0, false
+	}
+	if item.Value == "" {
+		return 0, true
+	}
+	if strings.HasPrefix(item.Value, "&") {
+		return radixNumber(item.Value)
+	}
+	if strings.Trim(item.Value, "0123456789+-.EeDd") != "" {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(strings.NewReplacer("D", "E", "d", "e").Replace(item.Value), 64)
+	return v, err == nil
+}
+
+// radixNumber returns the value of &H7F, &O17 (or &17) or &B1010 as a
+// 16-bit integer, like the literals of the program.
+func radixNumber(s string) (float64, bool) {
+	base, digits := 8, s[1:]
+	if digits != "" {
+		switch digits[0] {
+		case 'H', 'h':
+			base, digits = 16, digits[1:]
+		case 'O', 'o':
+			digits = digits[1:]
+		case 'B', 'b':
+			base, digits = 2, digits[1:]
+		}
+	}
+
+	v, err := strconv.ParseUint(digits, base, 64)
+	if err != nil || v > 0xFFFF {
+		return 0, false
+	}
+	return float64(int16(v)), true
+}
+
+const dataRuntime = `
+struct b2c_datum {
+    const char *str; /* the text of the item */
+    double num;      /* the value if isnum */
+    int isnum;
+    int line;        /* the line number of DATA */
+};
+static int b2c_data_ptr; /* the next item to READ */
+`
+
+const readRuntime = `
+static const struct b2c_datum *b2c_read(void)
+{
+    if (b2c_data[b2c_data_ptr].str == NULL) {
+        b2c_error(B2C_E_DATA);
+    }
+    return &b2c_data[b2c_data_ptr++];
+}
+
+static double b2c_read_num(void)
+{
+    const struct b2c_datum *d = b2c_read();
+
+    if (!d->isnum) {
+        b2c_line = d->line; /* the error is in DATA */
+        b2c_error(B2C_E_SYNTAX);
+    }
+    return d->num;
+}
+
+static b2c_str b2c_read_str(void)
+{
+    const struct b2c_datum *d = b2c_read();
+
+    return b2c_lit(d->str, (int)strlen(d->str));
+}
+`
@@ -0,0 +1,45 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const indentString = "    "
+
+// emitter accumulates lines of C code with indentation.
+type emitter struct {
+	buf    bytes.Buffer
+	indent int
+}
+
+// line writes one indented line.
+func (e *emitter) line(format string, a ...interface{}) {
+	e.buf.WriteString(strings.Repeat(indentString, e.indent))
+	fmt.Fprintf(&e.buf, format, a...)
+	e.buf.WriteString("\n")
+}
+
+// raw writes s as is.
+func (e *emitter) raw(s string) {
+	e.buf.WriteString(s)
+}
+
+func (e *emitter) blank() {
+	e.buf.WriteString("\n")
+}
+
+func (e *emitter) in() {
+	e.indent++
+}
+
+func (e *emitter) out() {
+	if e.indent > 0 {
+		e.indent--
+	}
+}
+
+func (e *emitter) String() string {
+	return e.buf.String()
+}
@@ -0,0 +1,306 @@
+package codegen
+
+import (
+	"strconv"
+
+	"github.com/ysh86/b2c/ast"
+)
+
+// Error trapping
+//
+// A runtime error calls b2c_error. Without ON ERROR GOTO it stops the
+// program with the message of GW-BASIC. With it, b2c_error jumps back to
+// main() with longjmp, and main() jumps to the handler. Every statement
+// of such a program is numbered, so that RESUME can go back to the
+// statement in error and RESUME NEXT to the end of it:
+//
+//	b2c_stmt_3: b2c_stmt = 3;
+//	vf_A = b2c_div(1, vf_B);
+//	b2c_next_3:;
+
+// errorTrap writes the start of main() where the errors come back to.
+func (g *Generator) errorTrap() {
+	if !g.trapping {
+		return
+	}
+	g.e.line("if (setjmp(b2c_trap) != 0) {")
+	g.e.in()
+	g.e.line("goto b2c_error_handler;")
+	g.e.out()
+	g.e.line("}")
+}
+
+// noResume writes the check at the end of main(): a handler must RESUME.
+func (g *Generator) noResume() {
+	if !g.trapping {
+		return
+	}
+	g.e.line("if (b2c_in_error) {")
+	g.e.in()
+	g.e.line("b2c_error(B2C_E_NO_RESUME);")
+	g.e.out()
+	g.e.line("}")
+}
+
+// resumePoint numbers the statement s if errors are trapped. It returns
+// the label at the end of s, or "" if s is not numbered.
+func (g *Generator) resumePoint(s ast.Statement) string {
+	if !g.trapping {
+		return ""
+	}
+	switch s.(type) {
+	case *ast.LineNoStatement, *ast.LabelStatement, *ast.DimStatement, *ast.DataStatement, *ast.DefFnStatement:
+		return ""
+	}
+
+	g.stmts++
+	g.e.line("b2c_stmt_%d: b2c_stmt = %d;", g.stmts, g.stmts)
+	return "b2c_next_" + strconv.Itoa(g.stmts)
+}
+
+func (g *Generator) onErrorStatement(s *ast.OnErrorStatement) {
+	if s.Name == nil {
+		g.e.line("b2c_on_error_goto(0);")
+		return
+	}
+
+	label := labelName(s.Name)
+	id, ok := g.handlers[label]
+	if !ok {
+		g.handlerLabels = append(g.handlerLabels, label)
+		id = len(g.handlerLabels)
+		g.handlers[label] = id
+	}
+	g.e.line("b2c_on_error_goto(%d);", id)
+}
+
+func (g *Generator) resumeStatement(s *ast.ResumeStatement) {
+	g.e.line("b2c_resume();")
+	switch {
+	case s.Next:
+		g.resumesNext = true
+		g.e.line("goto b2c_resume_next;")
+	case s.Name != nil:
+		g.e.line("goto %s;", labelName(s.Name))
+	default:
+		g.resumes = true
+		g.e.line("goto b2c_resume_stmt;")
+	}
+}
+
+// errorDispatchers writes the code that jumps to the handler and back.
+func (g *Generator) errorDispatchers(out *emitter) {
+	dispatch := func(label string, value string, cases []string, prelude ...string) {
+		out.blank()
+		out.line("%s:", label)
+		out.in()
+		for _, line := range prelude {
+			out.line("%s", line)
+		}
+		out.line("switch (%s) {", value)
+		for i, c := range cases {
+			out.line("case %d: goto %s;", i+1, c)
+		}
+		out.line("}")
+		out.line("return 0;")
+		out.out()
+	}
+
+	if g.trapping {
+		prelude := []string{}
+		if g.required["file"] {
+			prelude = append(prelude, "b2c_print_screen(); /* the error may be in PRINT # */")
+		}
+		dispatch("b2c_error_handler", "b2c_on_error", g.handlerLabels, prelude...)
+	}
+
+	stmts := func(prefix string) []string {
+		labels := []string{}
+		for id := 1; id <= g.stmts; id++ {
+			labels = append(labels, prefix+strconv.Itoa(id))
+		}
+		return labels
+	}
+	if g.resumes {
+		dispatch("b2c_resume_stmt", "b2c_err_stmt", stmts("b2c_stmt_"))
+	}
+	if g.resumesNext {
+		dispatch("b2c_resume_next", "b2c_err_stmt", stmts("b2c_next_"))
+	}
+}
+
+const coreRuntime = `
+static int b2c_line; /* the current BASIC line number */
+
+/* the error codes raised by the runtime, as in GW-BASIC */
+enum {
+    B2C_E_SYNTAX = 2,
+    B2C_E_RETURN = 3, /* RETURN without GOSUB */
+    B2C_E_DATA = 4, /* Out of DATA */
+    B2C_E_ILLEGAL = 5, /* Illegal function call */
+    B2C_E_OVERFLOW = 6,
+    B2C_E_MEMORY = 7, /* Out of memory */
+    B2C_E_SUBSCRIPT = 9, /* Subscript out of range */
+    B2C_E_DIV0 = 11, /* Division by zero */
+    B2C_E_TYPE = 13, /* Type mismatch */
+    B2C_E_STRLEN = 15, /* String too long */
+    B2C_E_UNDEFINED_FN = 18, /* Undefined user function */
+    B2C_E_NO_RESUME = 19,
+    B2C_E_RESUME = 20, /* RESUME without error */
+    B2C_E_FIELD = 50, /* FIELD overflow */
+    B2C_E_FILE_NUMBER = 52, /* Bad file number */
+    B2C_E_FILE_NOT_FOUND = 53,
+    B2C_E_FILE_MODE = 54, /* Bad file mode */
+    B2C_E_FILE_OPEN = 55, /* File already open */
+    B2C_E_IO = 57, /* Device I/O Error */
+    B2C_E_FILE_EXISTS = 58, /* File already exists */
+    B2C_E_INPUT = 62, /* Input past end */
+    B2C_E_RECORD = 63, /* Bad record number */
+    B2C_E_FILE_NAME = 64, /* Bad file name */
+    B2C_E_ACCESS = 75 /* Path/File Access Error */
+};
+
+static const char *const b2c_messages[] = {
+    [1] = "NEXT without FOR",
+    [2] = "Syntax error",
+    [3] = "RETURN without GOSUB",
+    [4] = "Out of DATA",
+    [5] = "Illegal function call",
+    [6] = "Overflow",
+    [7] = "Out of memory",
+    [8] = "Undefined line number",
+    [9] = "Subscript out of range",
+    [10] = "Duplicate Definition",
+    [11] = "Division by zero",
+    [12] = "Illegal direct",
+    [13] = "Type mismatch",
+    [14] = "Out of string space",
+    [15] = "String too long",
+    [16] = "String formula too complex",
+    [17] = "Can't continue",
+    [18] = "Undefined user function",
+    [19] = "No RESUME",
+    [20] = "RESUME without error",
+    [22] = "Missing operand",
+    [23] = "Line buffer overflow",
+    [24] = "Device Timeout",
+    [25] = "Device Fault",
+    [26] = "FOR without NEXT",
+    [27] = "Out of Paper",
+    [29] = "WHILE without WEND",
+    [30] = "WEND without WHILE",
+    [50] = "FIELD overflow",
+    [51] = "Internal error",
+    [52] = "Bad file number",
+    [53] = "File not found",
+    [54] = "Bad file mode",
+    [55] = "File already open",
+    [57] = "Device I/O Error",
+    [58] = "File already exists",
+    [61] = "Disk full",
+    [62] = "Input past end",
+    [63] = "Bad record number",
+    [64] = "Bad file name",
+    [66] = "Direct statement in file",
+    [67] = "Too many files",
+    [68] = "Device Unavailable",
+    [69] = "Communication buffer overflow",
+    [70] = "Permission Denied",
+    [71] = "Disk not Ready",
+    [72] = "Disk media error",
+    [73] = "Advanced Feature",
+    [74] = "Rename across disks",
+    [75] = "Path/File Access Error",
+    [76] = "Path not found",
+};
+
+static int b2c_err, b2c_erl; /* ERR and ERL */
+
+/*
+ * b2c_on_error is the handler of ON ERROR GOTO, 0 for none, and
+ * b2c_in_error is set while it runs. b2c_stmt is the statement being
+ * run, b2c_err_stmt the one in error.
+ */
+static int b2c_on_error, b2c_in_error;
+static int b2c_stmt, b2c_err_stmt;
+static jmp_buf b2c_trap;
+
+static void b2c_error(int code)
+{
+    const char *msg = "Unprintable error";
+
+    b2c_err = code;
+    b2c_erl = b2c_line;
+    b2c_err_stmt = b2c_stmt;
+    if (b2c_on_error != 0 && !b2c_in_error) {
+        b2c_in_error = 1;
+        longjmp(b2c_trap, 1);
+    }
+
+    if (code > 0 && code < (int)(sizeof b2c_messages / sizeof b2c_messages[0]) && b2c_messages[code] != NULL) {
+        msg = b2c_messages[code];
+    }
+    fflush(stdout);
+    if (b2c_line != 0) {
+        fprintf(stderr, "%s in %d\n", msg, b2c_line);
+    } else {
+        fprintf(stderr, "%s\n", msg);
+    }
+    exit(1);
+}
+
+/* b2c_end is END. exit flushes the output. */
+static void b2c_end(void)
+{
+    exit(0);
+}
+
+/* b2c_stop is STOP, which breaks the program like Ctrl-C. */
+static void b2c_stop(void)
+{
+    fflush(stdout);
+    if (b2c_line != 0) {
+        fprintf(stderr, "Break in %d\n", b2c_line);
+    } else {
+        fprintf(stderr, "Break\n");
+    }
+    exit(0);
+}
+
+/* b2c_raise is the ERROR statement. */
+static void b2c_raise(int code)
+{
+    if (code < 1 || code > 255) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    b2c_error(code);
+}
+
+/* ON ERROR GOTO 0 in a handler stops the program with the error. */
+static void b2c_on_error_goto(int handler)
+{
+    if (handler == 0 && b2c_in_error) {
+        b2c_on_error = 0;
+        b2c_line = b2c_erl;
+        b2c_error(b2c_err);
+    }
+    b2c_on_error = handler;
+}
+
+static void b2c_resume(void)
+{
+    if (!b2c_in_error) {
+        b2c_error(B2C_E_RESUME);
+    }
+    b2c_in_error = 0;
+}
+
+/* b2c_index checks the subscript i of an array whose upper bound is max. */
+static int b2c_index(int i, int max)
+{
+    if (i < 0 || i > max) {
+        b2c_error(B2C_E_SUBSCRIPT);
+    }
+    return i;
+}
+`
@@ -0,0 +1,500 @@
+package codegen
+
+import (
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/types"
+)
+
+// Files
+//
+// The files of OPEN are stdio streams kept in b2c_files by their number.
+// PRINT # and WRITE # write through b2c_putc like PRINT, which
+// b2c_print_file points to the file until b2c_print_screen, so the print
+// zones and TAB work in the files too:
+//
+//	b2c_print_file(1);
+//	b2c_print_int(vi_A);
+//	b2c_print_newline();
+//	b2c_print_screen();
+
+// writeFuncs are the runtime functions that WRITE a value of each type.
+var writeFuncs = map[types.Type]string{
+	types.Integer: "b2c_write_int",
+	types.Single:  "b2c_write_sng",
+	types.Double:  "b2c_write_dbl",
+	types.String:  "b2c_write_str",
+}
+
+func (g *Generator) openStatement(s *ast.OpenStatement) {
+	g.require("file")
+
+	length := "0"
+	if s.Length != nil {
+		length = g.intExpression(s.Length)
+	}
+	g.e.line("b2c_open(%s, %s, %s, %s);", g.expression(s.Mode), g.intExpression(s.Number), g.expression(s.Name), length)
+}
+
+func (g *Generator) closeStatement(s *ast.CloseStatement) {
+	g.require("file")
+
+	if len(s.Numbers) == 0 {
+		g.e.line("b2c_close_all();")
+		return
+	}
+	for _, n := range s.Numbers {
+		g.e.line("b2c_close(%s);", g.intExpression(n))
+	}
+}
+
+// printFile points the output of PRINT to file unless it is nil. It
+// returns the function that points it back to the screen.
+func (g *Generator) printFile(file ast.Expression) func() {
+	if file == nil {
+		return func() {}
+	}
+
+	g.require("file")
+	g.e.line("b2c_print_file(%s);", g.intExpression(file))
+	return func() {
+		g.e.line("b2c_print_screen();")
+	}
+}
+
+// writeStatement writes the values separated by commas, the strings in
+// quotes and the numbers without spaces.
+func (g *Generator) writeStatement(s *ast.WriteStatement) {
+	g.require("write")
+	defer g.printFile(s.File)()
+
+	for i, v := range s.Values {
+		if i > 0 {
+			g.e.line("b2c_putc(',');")
+		}
+		g.e.line("%s(%s);", writeFuncs[semantic.TypeOf(v)], g.expression(v))
+	}
+	g.e.line("b2c_print_newline();")
+}
+
+// inputFile reads the variables of INPUT # one by one, as the items of a
+// file need not be on one line.
+func (g *Generator) inputFile(s *ast.InputStatement) {
+	g.require("file")
+
+	g.e.line("b2c_input_file(%s);", g.intExpression(s.File))
+	for _, n := range s.Names {
+		switch semantic.TypeOf(n) {
+		case types.String:
+			g.assign(n, "b2c_input_file_str()")
+		case types.Integer:
+			g.require("cint")
+			g.assign(n, "b2c_cint(b2c_input_file_num())")
+		case types.Single:
+			g.assign(n, g.csng("b2c_input_file_num()"))
+		default:
+			g.assign(n, "b2c_input_file_num()")
+		}
+	}
+}
+
+func (g *Generator) killStatement(s *ast.KillStatement) {
+	g.require("file")
+	g.e.line("b2c_kill(%s);", g.expression(s.Name))
+}
+
+func (g *Generator) nameStatement(s *ast.NameStatement) {
+	g.require("file")
+	g.e.line("b2c_name(%s, %s);", g.expression(s.Old), g.expression(s.New))
+}
+
+const writeRuntime = `
+/* WRITE writes the numbers without the spaces of PRINT. */
+static void b2c_write_int(int n)
+{
+    char buf[16];
+
+    sprintf(buf, "%d", n);
+    b2c_puts(buf);
+}
+
+static void b2c_write_sng(double x)
+{
+    char buf[40], *p = b2c_fmtnum(buf, x, 7, 'E');
+
+    b2c_puts(*p == ' ' ? p + 1 : p);
+}
+
+static void b2c_write_dbl(double x)
+{
+    char buf[40], *p = b2c_fmtnum(buf, x, 16, 'D');
+
+    b2c_puts(*p == ' ' ? p + 1 : p);
+}
+
+static void b2c_write_str(b2c_str s)
+{
+    b2c_putc('"');
+    b2c_print_str(s);
+    b2c_putc('"');
+}
+`
+
+const fileRuntime = `
+#ifndef B2C_FILES
+#define B2C_FILES 15 /* the largest file number */
+#endif
+
+struct b2c_file {
+    FILE *fp; /* NULL if the number is not open */
+    int mode; /* 'I', 'O', 'A' or 'R' */
+    int pos;  /* the column of PRINT # */
+    char name[B2C_STR_MAX + 1];
+
+    /* a random file */
+    char *buf;  /* the record */
+    int reclen; /* the length of the record */
+    long rec;   /* the record of the last GET or PUT */
+    int eof;    /* whether the last GET read past the end */
+};
+
+static struct b2c_file b2c_files[B2C_FILES + 1];
+static FILE *b2c_in; /* the file of INPUT # */
+
+/* b2c_fields are the string variables of FIELD, see b2c_field. */
+static struct b2c_field {
+    b2c_str *var;
+    int file;
+    int offset, len; /* the bytes of the record */
+} *b2c_fields;
+static int b2c_nfields;
+
+/* b2c_file_name copies the name of a file s into buf. */
+static const char *b2c_file_name(b2c_str s, char *buf)
+{
+    if (s.len == 0 || memchr(s.p, '\0', s.len) != NULL) {
+        b2c_error(B2C_E_FILE_NAME);
+    }
+    memcpy(buf, s.p, s.len);
+    buf[s.len] = '\0';
+    return buf;
+}
+
+/* b2c_file_get returns the file n, which must be open in one of modes. */
+static struct b2c_file *b2c_file_get(int n, const char *modes)
+{
+    if (n < 1 || n > B2C_FILES || b2c_files[n].fp == NULL) {
+        b2c_error(B2C_E_FILE_NUMBER);
+    }
+    if (strchr(modes, b2c_files[n].mode) == NULL) {
+        b2c_error(B2C_E_FILE_MODE);
+    }
+    return &b2c_files[n];
+}
+
+/*
+ * b2c_file_busy reports whether the file name is open. A file may be
+ * open for input several times, so only the other modes count unless
+ * any is set.
+ */
+static int b2c_file_busy(const char *name, int any)
+{
+    int i;
+
+    for (i = 1; i <= B2C_FILES; i++) {
+        if (b2c_files[i].fp != NULL && (any || b2c_files[i].mode != 'I') &&
+            strcmp(b2c_files[i].name, name) == 0) {
+            return 1;
+        }
+    }
+    return 0;
+}
+
+static int b2c_file_exists(const char *name)
+{
+    FILE *fp = fopen(name, "rb");
+
+    if (fp == NULL) {
+        return 0;
+    }
+    fclose(fp);
+    return 1;
+}
+
+/*
+ * b2c_open opens the file name as the number n. mode is "I", "O", "A" or
+ * "R" for input, output, append or random. len is the record length, 0 if
+ * omitted, which only sizes the buffer of a sequential file.
+ */
+static void b2c_open(b2c_str mode, int n, b2c_str name, int len)
+{
+    char buf[B2C_STR_MAX + 1];
+    const char *path = b2c_file_name(name, buf);
+    struct b2c_file *f;
+    int m = mode.len > 0 ? mode.p[0] : 0;
+
+    if (m >= 'a' && m <= 'z') {
+        m -= 'a' - 'A';
+    }
+    if (m != 'I' && m != 'O' && m != 'A' && m != 'R') {
+        b2c_error(B2C_E_FILE_MODE);
+    }
+    if (n < 1 || n > B2C_FILES) {
+        b2c_error(B2C_E_FILE_NUMBER);
+    }
+    if (len < 0 || len > 32767) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    if (b2c_files[n].fp != NULL || b2c_file_busy(path, m != 'I')) {
+        b2c_error(B2C_E_FILE_OPEN);
+    }
+
+    f = &b2c_files[n];
+    if (m == 'R') {
+        f->reclen = len != 0 ? len : 128;
+        f->buf = calloc(f->reclen, 1);
+        if (f->buf == NULL) {
+            b2c_error(B2C_E_MEMORY);
+        }
+        f->fp = fopen(path, "r+b");
+        if (f->fp == NULL) {
+            f->fp = fopen(path, "w+b");
+        }
+    } else {
+        f->fp = fopen(path, m == 'I' ? "rb" : m == 'O' ? "wb" : "ab");
+    }
+    if (f->fp == NULL) {
+        free(f->buf);
+        f->buf = NULL;
+        b2c_error(m == 'I' ? B2C_E_FILE_NOT_FOUND : B2C_E_ACCESS);
+    }
+    f->mode = m;
+    f->pos = 0;
+    f->rec = 0;
+    f->eof = 0;
+    strcpy(f->name, path);
+}
+
+/*
+ * Closing a number that is not open is not an error. The variables of
+ * FIELD keep their values, but they are no longer in the record.
+ */
+static void b2c_close(int n)
+{
+    int i, k = 0;
+
+    if (n < 1 || n > B2C_FILES) {
+        b2c_error(B2C_E_FILE_NUMBER);
+    }
+    if (b2c_files[n].fp != NULL) {
+        fclose(b2c_files[n].fp);
+        b2c_files[n].fp = NULL;
+        free(b2c_files[n].buf);
+        b2c_files[n].buf = NULL;
+    }
+
+    for (i = 0; i < b2c_nfields; i++) {
+        if (b2c_fields[i].file != n) {
+            b2c_fields[k++] = b2c_fields[i];
+        }
+    }
+    b2c_nfields = k;
+}
+
+static void b2c_close_all(void)
+{
+    int i;
+
+    for (i = 1; i <= B2C_FILES; i++) {
+        b2c_close(i);
+    }
+}
+
+/* b2c_print_file makes PRINT write to the file n until b2c_print_screen. */
+static void b2c_print_file(int n)
+{
+    struct b2c_file *f = b2c_file_get(n, "OA");
+
+    b2c_out = f->fp;
+    b2c_out_pos = &f->pos;
+}
+
+/* b2c_fgetc reads a byte of fp. ^Z ends the file, as in MS-DOS. */
+static int b2c_fgetc(FILE *fp)
+{
+    int c = getc(fp);
+
+    if (c == 0x1A) {
+        ungetc(c, fp);
+        return EOF;
+    }
+    return c;
+}
+
+static void b2c_input_file(int n)
+{
+    b2c_in = b2c_file_get(n, "I")->fp;
+}
+
+/*
+ * b2c_input_item reads the next item of INPUT # into buf and returns its
+ * length. The spaces and the newlines before the item are skipped. A
+ * string is quoted, or ends at a comma or a newline without the spaces
+ * before them. A number also ends at a space.
+ */
+static int b2c_input_item(char *buf, int numeric)
+{
+    int c, n = 0;
+
+    do {
+        c = b2c_fgetc(b2c_in);
+    } while (c == ' ' || c == '\t' || c == '\r' || c == '\n');
+    if (c == EOF) {
+        b2c_error(B2C_E_INPUT);
+    }
+
+    if (c == '"' && !numeric) {
+        while ((c = b2c_fgetc(b2c_in)) != EOF && c != '"') {
+            if (n < B2C_STR_MAX) {
+                buf[n++] = (char)c;
+            }
+        }
+        if (c == '"') {
+            c = b2c_fgetc(b2c_in);
+        }
+    } else {
+        for (; c != EOF && c != ',' && c != '\r' && c != '\n'; c = b2c_fgetc(b2c_in)) {
+            if (numeric && c == ' ') {
+                break;
+            }
+            if (n < B2C_STR_MAX) {
+                buf[n++] = (char)c;
+            }
+        }
+        while (n > 0 && buf[n - 1] == ' ') {
+            n--;
+        }
+    }
+    while (c == ' ') {
+        c = b2c_fgetc(b2c_in);
+    }
+
+    /* the delimiter is read, anything else is left for the next item */
+    if (c == '\r') {
+        c = b2c_fgetc(b2c_in);
+        if (c != '\n' && c != EOF) {
+            ungetc(c, b2c_in);
+        }
+    } else if (c != ',' && c != '\n' && c != EOF) {
+        ungetc(c, b2c_in);
+    }
+    buf[n] = '\0';
+
+    return n;
+}
+
+static double b2c_input_file_num(void)
+{
+    char buf[B2C_STR_MAX + 1];
+
+    b2c_input_item(buf, 1);
+    return b2c_atof(buf);
+}
+
+static b2c_str b2c_input_file_str(void)
+{
+    char buf[B2C_STR_MAX + 1];
+    int n = b2c_input_item(buf, 0);
+
+    return b2c_substr(b2c_lit(buf, n), 0, n);
+}
+
+/* b2c_line_input_file reads a line of the file n without the newline. */
+static b2c_str b2c_line_input_file(int n)
+{
+    FILE *fp = b2c_file_get(n, "I")->fp;
+    char buf[B2C_STR_MAX + 1];
+    int c, len = 0;
+
+    c = b2c_fgetc(fp);
+    if (c == EOF) {
+        b2c_error(B2C_E_INPUT);
+    }
+    for (; c != EOF && c != '\n'; c = b2c_fgetc(fp)) {
+        if (c != '\r' && len < B2C_STR_MAX) {
+            buf[len++] = (char)c;
+        }
+    }
+
+    return b2c_substr(b2c_lit(buf, len), 0, len);
+}
+
+/*
+ * b2c_eof is EOF(n), -1 at the end of the file n. The end of a random
+ * file is a GET past it.
+ */
+static int b2c_eof(int n)
+{
+    struct b2c_file *f = b2c_file_get(n, "IR");
+    int c;
+
+    if (f->mode == 'R') {
+        return f->eof ? -1 : 0;
+    }
+    c = b2c_fgetc(f->fp);
+    if (c == EOF) {
+        return -1;
+    }
+    ungetc(c, f->fp);
+    return 0;
+}
+
+/* b2c_lof is LOF(n), the length of the file n in bytes. */
+static double b2c_lof(int n)
+{
+    FILE *fp = b2c_file_get(n, "IOAR")->fp;
+    long pos = ftell(fp), len;
+
+    fseek(fp, 0, SEEK_END);
+    len = ftell(fp);
+    fseek(fp, pos, SEEK_SET);
+    return (double)len;
+}
+
+static void b2c_kill(b2c_str name)
+{
+    char buf[B2C_STR_MAX + 1];
+    const char *path = b2c_file_name(name, buf);
+
+    if (b2c_file_busy(path, 1)) {
+        b2c_error(B2C_E_FILE_OPEN);
+    }
+    if (!b2c_file_exists(path)) {
+        b2c_error(B2C_E_FILE_NOT_FOUND);
+    }
+    if (remove(path) != 0) {
+        b2c_error(B2C_E_ACCESS);
+    }
+}
+
+/* b2c_name renames the file from to to, which must not exist. */
+static void b2c_name(b2c_str from, b2c_str to)
+{
+    char buf1[B2C_STR_MAX + 1], buf2[B2C_STR_MAX + 1];
+    const char *path1 = b2c_file_name(from, buf1);
+    const char *path2 = b2c_file_name(to, buf2);
+
+    if (!b2c_file_exists(path1)) {
+        b2c_error(B2C_E_FILE_NOT_FOUND);
+    }
+    if (b2c_file_exists(path2)) {
+        b2c_error(B2C_E_FILE_EXISTS);
+    }
+    if (b2c_file_busy(path1, 1)) {
+        b2c_error(B2C_E_FILE_OPEN);
+    }
+    if (rename(path1, path2) != 0) {
+        b2c_error(B2C_E_ACCESS);
+    }
+}
+`
@@ -0,0 +1,225 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/types"
+)
+
+// inputStatement reads a line of comma separated values. The runtime asks
+// again until the line has a value of the right kind for every variable,
+// so the assignments below cannot fail.
+func (g *Generator) inputStatement(s *ast.InputStatement) {
+	if s.File != nil {
+		g.inputFile(s)
+		return
+	}
+
+	g.require("input")
+
+	prompt := ""
+	if s.Prompt != nil {
+		prompt = s.Prompt.Value
+	}
+	if s.Question {
+		prompt += "? "
+	}
+
+	kinds := ""
+	for _, n := range s.Names {
+		if semantic.TypeOf(n) == types.String {
+			kinds += "s"
+		} else {
+			kinds += "n"
+		}
+	}
+
+	g.e.line("b2c_input(%s, %s);", cString(prompt), cString(kinds))
+	for i, n := range s.Names {
+		switch semantic.TypeOf(n) {
+		case types.String:
+			g.assign(n, fmt.Sprintf("b2c_input_str(%d)", i))
+		case types.Integer:
+			g.require("cint")
+			g.assign(n, fmt.Sprintf("b2c_cint(b2c_input_num(%d))", i))
+		case types.Single:
+			g.assign(n, g.csng(fmt.Sprintf("b2c_input_num(%d)", i)))
+		default:
+			g.assign(n, fmt.Sprintf("b2c_input_num(%d)", i))
+		}
+	}
+}
+
+func (g *Generator) lineInputStatement(s *ast.LineInputStatement) {
+	if s.File != nil {
+		g.require("file")
+		g.assign(s.Name, "b2c_line_input_file("+g.intExpression(s.File)+")")
+		return
+	}
+
+	g.require("input")
+
+	prompt := ""
+	if s.Prompt != nil {
+		prompt = s.Prompt.Value
+	}
+
+	g.assign(s.Name, "b2c_line_input("+cString(prompt)+")")
+}
+
+const inputRuntime = `
+static char b2c_input_buf[256]; /* the last line read by INPUT */
+static char **b2c_input_fields; /* the values in b2c_input_buf */
+static int b2c_input_cap;
+
+/* b2c_readline writes prompt and reads a line without the newline. */
+static char *b2c_readline(const char *prompt)
+{
+    size_t n;
+    int c;
+
+    b2c_puts(prompt);
+    fflush(stdout);
+    if (fgets(b2c_input_buf, sizeof b2c_input_buf, stdin) == NULL) {
+        b2c_error(B2C_E_INPUT);
+    }
+    n = strlen(b2c_input_buf);
+    if (n > 0 && b2c_input_buf[n - 1] != '\n') {
+        /* too long: drop the rest of the line */
+        while ((c = getchar()) != EOF && c != '\n') {
+        }
+    }
+    while (n > 0 && (b2c_input_buf[n - 1] == '\n' || b2c_input_buf[n - 1] == '\r')) {
+        b2c_input_buf[--n] = '\0';
+    }
+    b2c_pos = 0; /* the user typed a newline */
+
+    return b2c_input_buf;
+}
+
+/*
+ * b2c_input_split splits the line s into at most max fields separated by
+ * commas, trimming the spaces around them. A field may be quoted to have
+ * commas. It returns the number of fields, or -1 for a broken line.
+ */
+static int b2c_input_split(char *s, int max)
+{
+    int n = 0;
+    char *end;
+
+    if (max > b2c_input_cap) {
+        b2c_input_fields = realloc(b2c_input_fields, max * sizeof(char *));
+        if (b2c_input_fields == NULL) {
+            b2c_error(B2C_E_MEMORY);
+        }
+        b2c_input_cap = max;
+    }
+
+    for (;;) {
+        while (*s == ' ') {
+            s++;
+        }
+        if (n == max) {
+            return -1; /* too many values */
+        }
+        b2c_input_fields[n++] = s;
+
+        if (*s == '"') {
+            end = strchr(s + 1, '"');
+            if (end == NULL) {
+                return -1;
+            }
+            s = end + 1;
+            end = s;
+            while (*s == ' ') {
+                s++;
+            }
+            if (*s != ',' && *s != '\0') {
+                return -1;
+            }
+        } else {
+            while (*s != ',' && *s != '\0') {
+                s++;
+            }
+            for (end = s; end > b2c_input_fields[n - 1] && end[-1] == ' '; end--) {
+            }
+        }
+
+        if (*s == '\0') {
+            *end = '\0';
+            return n;
+        }
+        *end = '\0';
+        s++;
+    }
+}
+
+/* b2c_input_isnum converts s to *x if s is a number or empty. */
+static int b2c_input_isnum(const char *s, double *x)
+{
+    char tmp[256], *end;
+    int i;
+
+    for (i = 0; s[i] != '\0'; i++) {
+        if (strchr("0123456789+-.EeDd", s[i]) == NULL) {
+            return 0;
+        }
+        tmp[i] = s[i] == 'D' || s[i] == 'd' ? 'E' : s[i];
+    }
+    tmp[i] = '\0';
+
+    *x = i == 0 ? 0 : strtod(tmp, &end);
+    return i == 0 || *end == '\0';
+}
+
+/*
+ * b2c_input reads a line of values, one for each letter of kinds: n for a
+ * number and s for a string. It asks again until the line matches.
+ */
+static void b2c_input(const char *prompt, const char *kinds)
+{
+    int n = (int)strlen(kinds), i;
+    double x;
+
+    for (;;) {
+        if (b2c_input_split(b2c_readline(prompt), n) == n) {
+            for (i = 0; i < n; i++) {
+                if (kinds[i] == 'n' && !b2c_input_isnum(b2c_input_fields[i], &x)) {
+                    break;
+                }
+            }
+            if (i == n) {
+                return;
+            }
+        }
+        b2c_puts("?Redo from start\n");
+    }
+}
+
+static double b2c_input_num(int i)
+{
+    double x;
+
+    b2c_input_isnum(b2c_input_fields[i], &x);
+    return x;
+}
+
+static b2c_str b2c_input_str(int i)
+{
+    const char *s = b2c_input_fields[i];
+
+    if (*s == '"') {
+        return b2c_lit(s + 1, (int)(strchr(s + 1, '"') - (s + 1)));
+    }
+    return b2c_lit(s, (int)strlen(s));
+}
+
+static b2c_str b2c_line_input(const char *prompt)
+{
+    const char *s = b2c_readline(prompt);
+
+    return b2c_lit(s, (int)strlen(s));
+}
+`
@@ -0,0 +1,60 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/types"
+)
+
+// Name mangling
+//
+// Every BASIC name is given a prefix naming its namespace, so the generated
+// names never collide with each other, with C keywords, with libc symbols
+// (INT, CHAR, EXIT, LOG, ...) or with the runtime, which uses b2c_:
+//
+//	A, A!     vf_A     scalar (f: single, d: double, i: integer, s: string)
+//	A$        vs_A
+//	A(1)      af_A     array
+//	A$(1)     as_A
+//	FNA(1)    ff_A     user function
+//	FNA$(1)   fs_A
+//	*GOGO     L_GOGO   label
+//	100       N_100    line number
+//
+// A and A! are the same variable in BASIC, and so is their C name.
+
+var typeTags = map[types.Type]string{
+	types.Integer: "i",
+	types.Single:  "f",
+	types.Double:  "d",
+	types.String:  "s",
+}
+
+// scalarName returns the C name of the scalar variable name.
+func scalarName(name string) string {
+	return "v" + typeTags[types.FromName(name)] + "_" + types.BaseName(name)
+}
+
+// arrayName returns the C name of the array variable name.
+func arrayName(name string) string {
+	return "a" + typeTags[types.FromName(name)] + "_" + types.BaseName(name)
+}
+
+// funcName returns the C name of the user function name.
+func funcName(name string) string {
+	return "f" + typeTags[types.FromName(name)] + "_" + strings.TrimPrefix(types.BaseName(name), "FN")
+}
+
+// defName returns the C flag that the DEF of the user function name has run.
+func defName(name string) string {
+	return "b2c_def_" + funcName(name)
+}
+
+// labelName returns the C label of a line number or a '*' label.
+func labelName(name *ast.Identifier) string {
+	if name.IsLineNo() {
+		return "N_" + name.Value
+	}
+	return "L_" + name.Value
+}
@@ -0,0 +1,202 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/builtin"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/types"
+)
+
+// numericCall returns the call of the builtin numeric function f. It
+// returns false if f is not a numeric function.
+func (g *Generator) numericCall(f *builtin.Func, e *ast.CallExpression) (string, bool) {
+	args := e.Arguments
+	call := func(name string, a ...string) string {
+		return name + "(" + strings.Join(a, ", ") + ")"
+	}
+	arg := func() string {
+		return g.expression(args[0])
+	}
+	isInt := func() bool {
+		return semantic.TypeOf(args[0]) == types.Integer
+	}
+
+	switch f.Name {
+	case "ABS":
+		if isInt() {
+			g.require("int")
+			return call("b2c_int", call("abs", arg())), true // ABS(-32768)
+		}
+		return call("fabs", arg()), true
+	case "INT":
+		if isInt() {
+			return "(" + arg() + ")", true
+		}
+		return call("floor", arg()), true
+	case "FIX":
+		if isInt() {
+			return "(" + arg() + ")", true
+		}
+		return call("trunc", arg()), true
+	case "SIN", "COS", "TAN":
+		return call(strings.ToLower(f.Name), arg()), true
+	case "ATN":
+		return call("atan", arg()), true
+	case "SGN", "SQR", "LOG", "EXP":
+		g.require("math")
+		return call("b2c_"+strings.ToLower(f.Name), arg()), true
+	case "RND":
+		g.require("rnd")
+		if len(args) == 0 {
+			return call("b2c_rnd", "1"), true
+		}
+		return call("b2c_rnd", arg()), true
+	case "CINT":
+		return g.intExpression(args[0]), true
+	case "CSNG":
+		return arg(), true // see singleFuncs
+	case "CDBL":
+		return "((double)(" + arg() + "))", true
+	case "EOF", "LOF":
+		g.require("file")
+		return call("b2c_"+strings.ToLower(f.Name), g.intExpression(args[0])), true
+	case "CVI", "CVS", "CVD":
+		g.require("mbf")
+		return call("b2c_"+strings.ToLower(f.Name), arg()), true
+	case "ERR":
+		return "b2c_err", true
+	case "ERL":
+		return "b2c_erl", true
+	}
+	return "", false
+}
+
+// randomizeStatement seeds RND. Without a seed, it asks for one like the
+// interpreter does.
+func (g *Generator) randomizeStatement(s *ast.RandomizeStatement) {
+	g.require("rnd")
+
+	if s.Value == nil {
+		g.require("input")
+		g.e.line("b2c_input(%s, \"n\");", cString("Random number seed (-32768 to 32767)? "))
+		g.e.line("b2c_randomize(b2c_input_num(0));")
+		return
+	}
+	g.e.line("b2c_randomize(%s);", g.expression(s.Value))
+}
+
+const mathRuntime = `
+static int b2c_sgn(double x)
+{
+    return (x > 0) - (x < 0);
+}
+
+static double b2c_sqr(double x)
+{
+    if (x < 0) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    return sqrt(x);
+}
+
+static double b2c_log(double x)
+{
+    if (x <= 0) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    return log(x);
+}
+
+static double b2c_pow(double x, double y)
+{
+    if (x == 0 && y < 0) {
+        b2c_error(B2C_E_DIV0);
+    }
+    if (x < 0 && y != floor(y)) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    return pow(x, y);
+}
+
+static double b2c_div(double a, double b)
+{
+    if (b == 0) {
+        b2c_error(B2C_E_DIV0);
+    }
+    return a / b;
+}
+
+/* b2c_idiv and b2c_mod truncate toward 0, like C. */
+static int b2c_idiv(int a, int b)
+{
+    if (b == 0) {
+        b2c_error(B2C_E_DIV0);
+    }
+    return a / b;
+}
+
+static int b2c_mod(int a, int b)
+{
+    if (b == 0) {
+        b2c_error(B2C_E_DIV0);
+    }
+    return a % b;
+}
+
+static double b2c_exp(double x)
+{
+    if (x > 88.02969) { /* the largest single is about 1.7E+38 */
+        b2c_error(B2C_E_OVERFLOW);
+    }
+    return exp(x);
+}
+`
+
+const rndRuntime = `
+/*
+ * RND is the 24-bit linear congruential generator of GW-BASIC, so that a
+ * program without RANDOMIZE gets the same numbers: .1213501, .651861, ...
+ */
+static long b2c_rnd_seed = 5228370;
+
+static void b2c_rnd_next(void)
+{
+    b2c_rnd_seed = (b2c_rnd_seed * 214013L + 2531011L) & 0xFFFFFFL;
+}
+
+/*
+ * b2c_rnd returns the next number for x > 0 and the last one for x = 0.
+ * A negative x starts the sequence for x again, from the bytes of x as a
+ * single in the Microsoft binary format.
+ */
+static double b2c_rnd(double x)
+{
+    if (x < 0) {
+        float f = (float)x;
+        unsigned int bits; /* the IEEE single */
+        unsigned long mant, exp;
+
+        memcpy(&bits, &f, sizeof bits);
+        exp = (bits >> 23) & 0xFF;
+        mant = ((bits >> 8) & 0x800000UL) | (bits & 0x7FFFFFUL);
+        if (exp != 0) {
+            exp += 2;
+        }
+        b2c_rnd_seed = (long)((mant + exp) & 0xFFFFFFUL);
+    }
+    if (x != 0) {
+        b2c_rnd_next();
+    }
+    return (double)b2c_rnd_seed / 16777216.0;
+}
+
+/* b2c_randomize puts the 16 bits of the seed n above the lowest byte. */
+static void b2c_randomize(double n)
+{
+    long k = (long)n;
+
+    b2c_rnd_seed = ((k & 0xFFFFL) << 8) | (b2c_rnd_seed & 0xFFL);
+}
+`
@@ -0,0 +1,510 @@
+package codegen
+
+import (
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/token"
+	"github.com/ysh86/b2c/types"
+)
+
+// printFuncs are the runtime functions that print a value of each type.
+var printFuncs = map[types.Type]string{
+	types.Integer: "b2c_print_int",
+	types.Single:  "b2c_print_sng",
+	types.Double:  "b2c_print_dbl",
+	types.String:  "b2c_print_str",
+}
+
+func (g *Generator) printStatement(s *ast.PrintStatement) {
+	defer g.printFile(s.File)()
+
+	if s.Using != nil {
+		g.printUsing(s)
+		return
+	}
+
+	g.require("print")
+
+	newline := true
+	for _, item := range s.Items {
+		switch {
+		case item.Func == token.TAB:
+			g.e.line("b2c_print_tab(%s);", g.intExpression(item.Value))
+		case item.Func == token.SPC:
+			g.e.line("b2c_print_spc(%s);", g.intExpression(item.Value))
+		case item.Value != nil:
+			t := semantic.TypeOf(item.Value)
+			g.e.line("%s(%s);", printFuncs[t], g.convert(item.Value, t))
+		}
+
+		if item.Separator == token.COMMA {
+			g.e.line("b2c_print_zone();")
+		}
+		newline = item.Separator == ""
+	}
+
+	if newline {
+		g.e.line("b2c_print_newline();")
+	}
+}
+
+// printUsing formats the values with the fields of the format string.
+// TAB and SPC are not allowed, and the separators only delimit the values.
+func (g *Generator) printUsing(s *ast.PrintStatement) {
+	g.require("using")
+
+	g.e.line("b2c_using_begin(%s);", g.expression(s.Using))
+
+	newline := true
+	for _, item := range s.Items {
+		switch {
+		case item.Func != "":
+			g.errorf(s, "%s is not allowed in PRINT USING", item.Func)
+		case item.Value == nil:
+		case semantic.TypeOf(item.Value) == types.String:
+			g.e.line("b2c_using_str(%s);", g.expression(item.Value))
+		default:
+			t := semantic.TypeOf(item.Value)
+			digits := 7
+			if t == types.Double {
+				digits = 16
+			}
+			g.e.line("b2c_using_num(%s, %d);", g.convert(item.Value, t), digits)
+		}
+		newline = item.Separator == ""
+	}
+
+	if newline {
+		g.e.line("b2c_using_end(1);")
+	} else {
+		g.e.line("b2c_using_end(0);")
+	}
+}
+
+const fmtnumRuntime = `
+/*
+ * b2c_fmtnum formats x like PRINT and STR$: a '-' or a space, then x
+ * rounded to digits significant digits. x is written in fixed notation
+ * when it fits in digits digits, otherwise like 1.5E+12, where e is the
+ * letter of the exponent. buf must have room for 40 characters.
+ */
+static char *b2c_fmtnum(char *buf, double x, int digits, char e)
+{
+    char tmp[40], mant[20], *p = buf, *q;
+    int exp, n, i;
+
+    *p++ = x < 0 ? '-' : ' ';
+    if (x < 0) {
+        x = -x;
+    }
+
+    sprintf(tmp, "%.*e", digits - 1, x); /* d.ddde+XX */
+    q = strchr(tmp, 'e');
+    if (q == NULL) { /* inf or nan */
+        strcpy(p, tmp);
+        return buf;
+    }
+    exp = atoi(q + 1);
+
+    /* the significant digits without trailing zeros */
+    n = 0;
+    for (i = 0; &tmp[i] != q; i++) {
+        if (tmp[i] != '.') {
+            mant[n++] = tmp[i];
+        }
+    }
+    while (n > 1 && mant[n - 1] == '0') {
+        n--;
+    }
+
+    if (x == 0) {
+        *p++ = '0';
+    } else if (exp >= 0 && exp < digits) {
+        for (i = 0; i <= exp; i++) {
+            *p++ = i < n ? mant[i] : '0';
+        }
+        if (n > exp + 1) {
+            *p++ = '.';
+            for (; i < n; i++) {
+                *p++ = mant[i];
+            }
+        }
+    } else if (exp < 0 && n - exp - 1 <= digits) {
+        *p++ = '.'; /* no leading zero: .5 */
+        for (i = exp + 1; i < 0; i++) {
+            *p++ = '0';
+        }
+        for (i = 0; i < n; i++) {
+            *p++ = mant[i];
+        }
+    } else {
+        *p++ = mant[0];
+        if (n > 1) {
+            *p++ = '.';
+            for (i = 1; i < n; i++) {
+                *p++ = mant[i];
+            }
+        }
+        p += sprintf(p, "%c%c%02d", e, exp < 0 ? '-' : '+', exp < 0 ? -exp : exp);
+    }
+    *p = '\0';
+
+    return buf;
+}
+`
+
+const printRuntime = `
+static int b2c_pos; /* the column of the cursor, starting at 0 */
+
+/* b2c_out is the file PRINT # writes to, NULL for the screen. */
+static FILE *b2c_out;
+static int *b2c_out_pos = &b2c_pos; /* the column in b2c_out */
+
+static void b2c_putc(int c)
+{
+    if (b2c_out != NULL) {
+        putc(c, b2c_out);
+    } else {
+        putchar(c);
+    }
+    *b2c_out_pos = c == '\n' ? 0 : *b2c_out_pos + 1;
+}
+
+static void b2c_print_screen(void)
+{
+    b2c_out = NULL;
+    b2c_out_pos = &b2c_pos;
+}
+
+static void b2c_puts(const char *s)
+{
+    while (*s != '\0') {
+        b2c_putc((unsigned char)*s++);
+    }
+}
+
+static void b2c_print_newline(void)
+{
+    b2c_putc('\n');
+}
+
+/* b2c_print_zone moves to the next print zone of 14 columns. */
+static void b2c_print_zone(void)
+{
+    do {
+        b2c_putc(' ');
+    } while (*b2c_out_pos % 14 != 0);
+}
+
+/* b2c_print_tab moves to the column n, starting at 1. */
+static void b2c_print_tab(int n)
+{
+    if (n < 1) {
+        n = 1;
+    }
+    if (*b2c_out_pos > n - 1) {
+        b2c_print_newline();
+    }
+    while (*b2c_out_pos < n - 1) {
+        b2c_putc(' ');
+    }
+}
+
+static void b2c_print_spc(int n)
+{
+    while (n-- > 0) {
+        b2c_putc(' ');
+    }
+}
+
+static void b2c_print_str(b2c_str s)
+{
+    int i;
+
+    for (i = 0; i < s.len; i++) {
+        b2c_putc((unsigned char)s.p[i]);
+    }
+}
+
+/* Numbers are followed by a space, and preceded by one unless negative. */
+static void b2c_print_int(int n)
+{
+    char buf[16];
+
+    sprintf(buf, "% d ", n);
+    b2c_puts(buf);
+}
+
+static void b2c_print_sng(double x)
+{
+    char buf[40];
+
+    b2c_puts(b2c_fmtnum(buf, x, 7, 'E'));
+    b2c_putc(' ');
+}
+
+static void b2c_print_dbl(double x)
+{
+    char buf[40];
+
+    b2c_puts(b2c_fmtnum(buf, x, 16, 'D'));
+    b2c_putc(' ');
+}
+`
+
+const usingRuntime = `
+#define B2C_USING_DIGITS 24 /* the most digits of a numeric field */
+
+static char b2c_using_fmt[B2C_STR_MAX + 1]; /* the format of PRINT USING */
+static const char *b2c_using_p;              /* the rest of the format */
+
+static void b2c_using_begin(b2c_str fmt)
+{
+    if (fmt.len > 0) {
+        memcpy(b2c_using_fmt, fmt.p, fmt.len);
+    }
+    b2c_using_fmt[fmt.len] = '\0';
+    b2c_using_p = b2c_using_fmt;
+}
+
+/* b2c_using_isnum reports whether a numeric field starts at p. */
+static int b2c_using_isnum(const char *p)
+{
+    if (*p == '+') {
+        p++;
+    }
+    return p[0] == '#' || (p[0] == '.' && p[1] == '#') ||
+        (p[0] == '*' && p[1] == '*') || (p[0] == '$' && p[1] == '$');
+}
+
+/* b2c_using_isstr reports whether a string field starts at p. */
+static int b2c_using_isstr(const char *p)
+{
+    if (*p == '!' || *p == '&') {
+        return 1;
+    }
+    if (*p == '\\') {
+        for (p++; *p == ' '; p++) {
+        }
+        return *p == '\\';
+    }
+    return 0;
+}
+
+/*
+ * b2c_using_literal writes the format up to the next field. It returns 0
+ * if the end of the format is reached.
+ */
+static int b2c_using_literal(void)
+{
+    while (*b2c_using_p != '\0') {
+        if (b2c_using_isnum(b2c_using_p) || b2c_using_isstr(b2c_using_p)) {
+            return 1;
+        }
+        if (*b2c_using_p == '_' && b2c_using_p[1] != '\0') {
+            b2c_using_p++; /* _ makes the next character literal */
+        }
+        b2c_putc((unsigned char)*b2c_using_p++);
+    }
+    return 0;
+}
+
+/* b2c_using_field moves to the next field, reusing the format if needed. */
+static void b2c_using_field(int numeric)
+{
+    if (!b2c_using_literal()) {
+        b2c_using_p = b2c_using_fmt;
+        if (!b2c_using_literal()) {
+            b2c_error(B2C_E_ILLEGAL);
+        }
+    }
+    if (numeric ? !b2c_using_isnum(b2c_using_p) : !b2c_using_isstr(b2c_using_p)) {
+        b2c_error(B2C_E_TYPE);
+    }
+}
+
+/*
+ * b2c_using_num writes x in the next numeric field. x has digits
+ * significant digits, 7 or 16, like b2c_fmtnum if it is too large for
+ * the digits of the field.
+ */
+static void b2c_using_num(double x, int digits)
+{
+    const char *p;
+    char num[64], body[96], *q;
+    int plus = 0, trail = 0, fill = ' ', dollar = 0, comma = 0, point = 0;
+    int left = 0, right = 0, expo = 0, neg = x < 0, over = 0, width, len, i, n;
+
+    b2c_using_field(1);
+    p = b2c_using_p;
+
+    /* parse the field */
+    if (*p == '+') {
+        plus = 1;
+        p++;
+    }
+    if (p[0] == '*' && p[1] == '*') {
+        fill = '*';
+        left += 2;
+        p += 2;
+        if (*p == '$') {
+            dollar = 1;
+            p++;
+        }
+    } else if (p[0] == '$' && p[1] == '$') {
+        dollar = 1;
+        left++;
+        p += 2;
+    }
+    for (; *p == '#' || *p == ','; p++) {
+        left++;
+        if (*p == ',') {
+            comma = 1;
+        }
+    }
+    if (*p == '.') {
+        point = 1;
+        for (p++; *p == '#'; p++) {
+            right++;
+        }
+    }
+    if (strncmp(p, "^^^^", 4) == 0) {
+        expo = 1;
+        p += 4;
+    }
+    if (!plus && (*p == '+' || *p == '-')) {
+        trail = *p;
+    }
+    width = (int)(p - b2c_using_p);
+    b2c_using_p = trail ? p + 1 : p;
+    if (left + right > B2C_USING_DIGITS) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+
+    if (neg) {
+        x = -x;
+    }
+
+    /* the digits */
+    q = num;
+    if (expo) {
+        /* a position of the integer part is kept for the sign */
+        int k = left - (plus || trail ? 0 : 1), e;
+        char tmp[64];
+
+        if (k + right < 1) {
+            k = 1;
+        }
+        snprintf(tmp, sizeof tmp, "%.*e", k + right - 1, x);
+        e = atoi(strchr(tmp, 'e') + 1) - (k - 1);
+        n = 0;
+        for (i = 0; tmp[i] != 'e'; i++) {
+            if (tmp[i] != '.') {
+                tmp[n++] = tmp[i];
+            }
+        }
+        for (i = 0; i < k; i++) {
+            *q++ = tmp[i];
+        }
+        if (point) {
+            *q++ = '.';
+            for (; i < k + right; i++) {
+                *q++ = tmp[i];
+            }
+        }
+        snprintf(q, sizeof num - (q - num), "E%c%02d", e < 0 ? '-' : '+', e < 0 ? -e : e);
+    } else if (x >= 1e24) {
+        /* more digits than a field has: the number like PRINT */
+        char tmp[40];
+
+        over = 1;
+        b2c_fmtnum(tmp, x, digits, digits > 7 ? 'D' : 'E');
+        snprintf(num, sizeof num, "%s", tmp + 1);
+    } else {
+        char tmp[64], *dot;
+
+        snprintf(tmp, sizeof tmp, "%.*f", right, x);
+        dot = strchr(tmp, '.');
+        n = dot != NULL ? (int)(dot - tmp) : (int)strlen(tmp);
+        if (!(n == 1 && tmp[0] == '0' && left == 0)) {
+            for (i = 0; i < n; i++) {
+                *q++ = tmp[i];
+                if (comma && i < n - 1 && (n - 1 - i) % 3 == 0) {
+                    *q++ = ',';
+                }
+            }
+        }
+        if (point) {
+            *q++ = '.';
+            if (dot != NULL) {
+                strcpy(q, dot + 1);
+                q += strlen(q);
+            }
+        }
+        *q = '\0';
+    }
+
+    /* the sign and the currency */
+    q = body;
+    if (plus) {
+        *q++ = neg ? '-' : '+';
+    } else if (neg && !trail) {
+        *q++ = '-';
+    }
+    if (dollar) {
+        *q++ = '$';
+    }
+    snprintf(q, sizeof body - (q - body), "%s", num);
+
+    len = (int)strlen(body);
+    if (over || len > width) {
+        b2c_putc('%'); /* the field is too small */
+    }
+    for (; len < width; len++) {
+        b2c_putc(fill);
+    }
+    b2c_puts(body);
+
+    if (trail == '+') {
+        b2c_putc(neg ? '-' : '+');
+    } else if (trail == '-') {
+        b2c_putc(neg ? '-' : ' ');
+    }
+}
+
+static void b2c_using_str(b2c_str s)
+{
+    int n, i;
+
+    b2c_using_field(0);
+
+    switch (*b2c_using_p) {
+    case '!':
+        b2c_putc(s.len > 0 ? (unsigned char)s.p[0] : ' ');
+        b2c_using_p++;
+        return;
+    case '&':
+        b2c_print_str(s);
+        b2c_using_p++;
+        return;
+    }
+
+    /* \  \ is as wide as the backslashes and the spaces between them */
+    for (n = 2, b2c_using_p++; *b2c_using_p == ' '; b2c_using_p++) {
+        n++;
+    }
+    b2c_using_p++;
+    for (i = 0; i < n; i++) {
+        b2c_putc(i < s.len ? (unsigned char)s.p[i] : ' ');
+    }
+}
+
+/* b2c_using_end writes the literal text after the last field. */
+static void b2c_using_end(int newline)
+{
+    b2c_using_literal();
+    if (newline) {
+        b2c_print_newline();
+    }
+}
+`
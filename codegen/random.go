@@ -0,0 +1,327 @@
+package codegen
+
+import (
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/token"
+)
+
+// Random files
+//
+// A random file has a buffer of one record, read by GET and written by
+// PUT. The variables of FIELD own a copy of their bytes of the record
+// like the other strings, which GET, LSET and RSET update. A variable
+// assigned by LET, INPUT or READ is no longer a field, so that it keeps
+// its value:
+//
+//	b2c_field_begin(1);
+//	b2c_field(&vs_N, 20);
+//	b2c_lset(&vs_N, vs_A);
+//	b2c_put(1, b2c_record(vf_R));
+
+func (g *Generator) fieldStatement(s *ast.FieldStatement) {
+	g.require("random")
+
+	g.e.line("b2c_field_begin(%s);", g.intExpression(s.File))
+	for i, w := range s.Widths {
+		g.e.line("b2c_field(&%s, %s);", g.identifier(s.Names[i]), g.intExpression(w))
+	}
+}
+
+func (g *Generator) recordStatement(s *ast.RecordStatement) {
+	g.require("random")
+
+	record := "0" // the next record
+	if s.Record != nil {
+		record = "b2c_record(" + g.expression(s.Record) + ")"
+	}
+	if s.Token.Type == token.GET {
+		g.e.line("b2c_get(%s, %s);", g.intExpression(s.File), record)
+	} else {
+		g.e.line("b2c_put(%s, %s);", g.intExpression(s.File), record)
+	}
+}
+
+func (g *Generator) setStatement(s *ast.SetStatement) {
+	g.require("random")
+
+	if s.Token.Type == token.LSET {
+		g.e.line("b2c_lset(&%s, %s);", g.identifier(s.Name), g.expression(s.Value))
+	} else {
+		g.e.line("b2c_rset(&%s, %s);", g.identifier(s.Name), g.expression(s.Value))
+	}
+}
+
+const randomRuntime = `
+static int b2c_fields_cap;
+static int b2c_field_file, b2c_field_offset; /* the FIELD being run */
+
+static void b2c_field_begin(int n)
+{
+    b2c_file_get(n, "R");
+    b2c_field_file = n;
+    b2c_field_offset = 0;
+}
+
+/* b2c_field_sync copies the record into the fields of the file n in it. */
+static void b2c_field_sync(int n)
+{
+    struct b2c_file *f = &b2c_files[n];
+    int i;
+
+    for (i = 0; i < b2c_nfields; i++) {
+        if (b2c_fields[i].file == n) {
+            b2c_str_set(b2c_fields[i].var, b2c_lit(f->buf + b2c_fields[i].offset, b2c_fields[i].len));
+        }
+    }
+}
+
+/* b2c_field_remove makes v an ordinary variable, see b2c_str_assign. */
+static void b2c_field_remove(b2c_str *v)
+{
+    int i, k = 0;
+
+    for (i = 0; i < b2c_nfields; i++) {
+        if (b2c_fields[i].var != v) {
+            b2c_fields[k++] = b2c_fields[i];
+        }
+    }
+    b2c_nfields = k;
+}
+
+/* b2c_field makes v the next len bytes of the record of FIELD. */
+static void b2c_field(b2c_str *v, int len)
+{
+    struct b2c_file *f = &b2c_files[b2c_field_file];
+    struct b2c_field *fd = NULL;
+    int i;
+
+    if (len < 0 || len > B2C_STR_MAX) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    if (b2c_field_offset + len > f->reclen) {
+        b2c_error(B2C_E_FIELD);
+    }
+
+    for (i = 0; i < b2c_nfields; i++) {
+        if (b2c_fields[i].var == v) {
+            fd = &b2c_fields[i];
+        }
+    }
+    if (fd == NULL) {
+        if (b2c_nfields == b2c_fields_cap) {
+            int cap = b2c_fields_cap == 0 ? 16 : b2c_fields_cap * 2;
+            struct b2c_field *p = realloc(b2c_fields, cap * sizeof b2c_fields[0]);
+
+            if (p == NULL) {
+                b2c_error(B2C_E_MEMORY);
+            }
+            b2c_fields = p;
+            b2c_fields_cap = cap;
+        }
+        fd = &b2c_fields[b2c_nfields++];
+    }
+    fd->var = v;
+    fd->file = b2c_field_file;
+    fd->offset = b2c_field_offset;
+    fd->len = len;
+    b2c_field_offset += len;
+    b2c_unfield = b2c_field_remove;
+
+    b2c_str_set(v, b2c_lit(f->buf + fd->offset, len));
+}
+
+/*
+ * b2c_set is LSET, or RSET if right is set: it writes s into v padded
+ * with spaces to the width of v, and into the record if v is a field.
+ */
+static void b2c_set(b2c_str *v, b2c_str s, int right)
+{
+    struct b2c_field *fd = NULL;
+    b2c_str t;
+    int n, i;
+
+    for (i = 0; i < b2c_nfields; i++) {
+        if (b2c_fields[i].var == v) {
+            fd = &b2c_fields[i];
+        }
+    }
+
+    t = b2c_temp(fd != NULL ? fd->len : v->len);
+    n = s.len < t.len ? s.len : t.len;
+    memset(t.p, ' ', t.len);
+    if (n > 0) {
+        memcpy(t.p + (right ? t.len - n : 0), s.p, n);
+    }
+
+    if (fd == NULL) {
+        b2c_str_assign(v, t);
+        return;
+    }
+    memcpy(b2c_files[fd->file].buf + fd->offset, t.p, t.len);
+    b2c_field_sync(fd->file); /* the fields may overlap */
+}
+
+static void b2c_lset(b2c_str *v, b2c_str s)
+{
+    b2c_set(v, s, 0);
+}
+
+static void b2c_rset(b2c_str *v, b2c_str s)
+{
+    b2c_set(v, s, 1);
+}
+
+/* b2c_record checks the record number r of GET and PUT. */
+static long b2c_record(double r)
+{
+    if (r < 1 || r > 16777215) {
+        b2c_error(B2C_E_RECORD);
+    }
+    return (long)r;
+}
+
+/* b2c_get reads the record rec, or the next one if rec is 0. */
+static void b2c_get(int n, long rec)
+{
+    struct b2c_file *f = b2c_file_get(n, "R");
+    size_t got = 0;
+
+    if (rec == 0) {
+        rec = f->rec + 1;
+    }
+    if (fseek(f->fp, (rec - 1) * f->reclen, SEEK_SET) == 0) {
+        got = fread(f->buf, 1, f->reclen, f->fp);
+    }
+    memset(f->buf + got, 0, f->reclen - got);
+    f->rec = rec;
+    f->eof = got < (size_t)f->reclen;
+
+    b2c_field_sync(n);
+}
+
+/* b2c_put writes the record rec, or the next one if rec is 0. */
+static void b2c_put(int n, long rec)
+{
+    struct b2c_file *f = b2c_file_get(n, "R");
+
+    if (rec == 0) {
+        rec = f->rec + 1;
+    }
+    if (fseek(f->fp, (rec - 1) * f->reclen, SEEK_SET) != 0 ||
+        fwrite(f->buf, 1, f->reclen, f->fp) != (size_t)f->reclen) {
+        b2c_error(B2C_E_IO);
+    }
+    f->rec = rec;
+}
+`
+
+// mbfRuntime converts the numbers to and from the bytes of MKI$, MKS$
+// and MKD$. The floats are in the Microsoft binary format of GW-BASIC.
+const mbfRuntime = `
+/*
+ * A number of the Microsoft binary format is 0.1mmm... * 2^(exp - 128),
+ * stored as the low bytes of the mantissa, then the sign bit in the place
+ * of the leading 1, then exp. exp is 0 for 0. A single has 24 bits of
+ * mantissa and a double 56 bits.
+ */
+static b2c_str b2c_mkmbf(double x, int size)
+{
+    b2c_str s = b2c_temp(size);
+    int bits = 8 * (size - 1), e, i;
+    unsigned long long m;
+    double f;
+
+    memset(s.p, 0, size);
+    if (!isfinite(x)) {
+        b2c_error(B2C_E_OVERFLOW);
+    }
+    f = frexp(fabs(x), &e);
+    if (f == 0) {
+        return s;
+    }
+
+    /* round to the bits of the mantissa, to the even like (float) */
+    m = (unsigned long long)rint(ldexp(f, bits));
+    if (m >> bits != 0) {
+        m >>= 1;
+        e++;
+    }
+    if (e + 128 < 1) {
+        return s; /* too small */
+    }
+    if (e + 128 > 255) {
+        b2c_error(B2C_E_OVERFLOW);
+    }
+
+    for (i = 0; i < size - 1; i++) {
+        s.p[i] = (char)((m >> (8 * i)) & 0xFF);
+    }
+    s.p[size - 2] = (char)((s.p[size - 2] & 0x7F) | (x < 0 ? 0x80 : 0));
+    s.p[size - 1] = (char)(e + 128);
+    return s;
+}
+
+static double b2c_cvmbf(b2c_str s, int size)
+{
+    const unsigned char *p = (const unsigned char *)s.p;
+    unsigned long long m = 0;
+    double x;
+    int i;
+
+    if (s.len < size) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    if (p[size - 1] == 0) {
+        return 0;
+    }
+
+    for (i = size - 2; i >= 0; i--) {
+        m = (m << 8) | p[i];
+    }
+    m |= 1ULL << (8 * (size - 1) - 1); /* the leading 1 */
+    x = ldexp((double)m, p[size - 1] - 128 - 8 * (size - 1));
+    return p[size - 2] & 0x80 ? -x : x;
+}
+
+/* b2c_mki is MKI$, the 2 bytes of the integer n, low byte first. */
+static b2c_str b2c_mki(int n)
+{
+    b2c_str s = b2c_temp(2);
+
+    s.p[0] = (char)(n & 0xFF);
+    s.p[1] = (char)((n >> 8) & 0xFF);
+    return s;
+}
+
+static b2c_str b2c_mks(double x)
+{
+    return b2c_mkmbf(x, 4);
+}
+
+static b2c_str b2c_mkd(double x)
+{
+    return b2c_mkmbf(x, 8);
+}
+
+static int b2c_cvi(b2c_str s)
+{
+    const unsigned char *p = (const unsigned char *)s.p;
+    int n;
+
+    if (s.len < 2) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    n = p[0] | (p[1] << 8);
+    return n >= 0x8000 ? n - 0x10000 : n;
+}
+
+static double b2c_cvs(b2c_str s)
+{
+    return b2c_cvmbf(s, 4);
+}
+
+static double b2c_cvd(b2c_str s)
+{
+    return b2c_cvmbf(s, 8);
+}
+`
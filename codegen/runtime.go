@@ -0,0 +1,189 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const prologue = `/* Generated by b2c. */
+#include <stdio.h>
+#include <math.h>
+#include <setjmp.h>
+#include <stdlib.h>
+#include <string.h>
+`
+
+const epilogue = `
+    fflush(stdout);
+    return 0;
+`
+
+// runtimeSection is a piece of the C runtime emitted on demand.
+type runtimeSection struct {
+	name string
+	deps []string
+	code string
+}
+
+// runtimeSections are emitted in this order.
+var runtimeSections = []runtimeSection{
+	{name: "core", code: coreRuntime},
+	{
+		name: "cint",
+		deps: []string{"core"},
+		code: `
+/* b2c_cint rounds x to a 16-bit integer like CINT. */
+static int b2c_cint(double x)
+{
+    if (x < -32768.5 || x >= 32767.5) {
+        b2c_error(B2C_E_OVERFLOW);
+    }
+    return x >= 0 ? (int)(x + 0.5) : -(int)(-x + 0.5);
+}
+`,
+	},
+	{
+		name: "int",
+		deps: []string{"core"},
+		code: `
+/* b2c_int checks that n, the result of an integer operation, is 16-bit. */
+static int b2c_int(int n)
+{
+    if (n < -32768 || n > 32767) {
+        b2c_error(B2C_E_OVERFLOW);
+    }
+    return n;
+}
+`,
+	},
+	{
+		name: "csng",
+		deps: []string{"core"},
+		code: `
+#define B2C_SNG_MAX 1.70141173e38 /* the largest single of GW-BASIC */
+
+/* b2c_csng rounds x to a single, checking that it is in the range. */
+static float b2c_csng(double x)
+{
+    if (x > B2C_SNG_MAX || x < -B2C_SNG_MAX) {
+        b2c_error(B2C_E_OVERFLOW);
+    }
+    return (float)x;
+}
+`,
+	},
+	{
+		name: "gosub",
+		deps: []string{"core"},
+		code: `
+static struct {
+    int id;   /* the return point */
+    int line; /* b2c_line of the GOSUB */
+} b2c_gosub_stack[B2C_GOSUB_DEPTH];
+static int b2c_gosub_sp;
+
+static void b2c_gosub_push(int id)
+{
+    if (b2c_gosub_sp >= B2C_GOSUB_DEPTH) {
+        b2c_error(B2C_E_MEMORY); /* GOSUB nesting too deep */
+    }
+    b2c_gosub_stack[b2c_gosub_sp].id = id;
+    b2c_gosub_stack[b2c_gosub_sp].line = b2c_line;
+    b2c_gosub_sp++;
+}
+
+static int b2c_gosub_pop(void)
+{
+    if (b2c_gosub_sp == 0) {
+        b2c_error(B2C_E_RETURN);
+    }
+    b2c_gosub_sp--;
+    b2c_line = b2c_gosub_stack[b2c_gosub_sp].line;
+    return b2c_gosub_stack[b2c_gosub_sp].id;
+}
+`,
+	},
+	{name: "math", deps: []string{"core"}, code: mathRuntime},
+	{name: "rnd", code: rndRuntime},
+	{name: "str", deps: []string{"core"}, code: strRuntime},
+	{name: "fmtnum", code: fmtnumRuntime},
+	{name: "strfn", deps: []string{"core", "str", "fmtnum"}, code: strfnRuntime},
+	{name: "print", deps: []string{"str", "fmtnum"}, code: printRuntime},
+	{name: "using", deps: []string{"core", "fmtnum", "print"}, code: usingRuntime},
+	{name: "input", deps: []string{"core", "str", "print"}, code: inputRuntime},
+	{name: "data", code: dataRuntime},
+	{name: "read", deps: []string{"core", "str", "data"}, code: readRuntime},
+	{name: "write", deps: []string{"print"}, code: writeRuntime},
+	{name: "file", deps: []string{"core", "str", "strfn", "print"}, code: fileRuntime},
+	{name: "random", deps: []string{"file"}, code: randomRuntime},
+	{name: "mbf", deps: []string{"core", "str"}, code: mbfRuntime},
+}
+
+// require marks the runtime section name and its dependencies as used.
+func (g *Generator) require(name string) {
+	if g.required[name] {
+		return
+	}
+	g.required[name] = true
+
+	for _, s := range runtimeSections {
+		if s.name == name {
+			for _, d := range s.deps {
+				g.require(d)
+			}
+			return
+		}
+	}
+	panic("codegen: unknown runtime section " + name)
+}
+
+// runtime writes the required runtime sections.
+func (g *Generator) runtime(out *emitter) {
+	for _, s := range runtimeSections {
+		if !g.required[s.name] {
+			continue
+		}
+		if s.name == "gosub" {
+			out.blank()
+			out.line("#ifndef B2C_GOSUB_DEPTH")
+			out.line("#define B2C_GOSUB_DEPTH %d", g.gosubDepth())
+			out.line("#endif")
+		}
+		out.raw(s.code)
+		if s.name == "data" {
+			g.dataTable(out)
+		}
+	}
+}
+
+// cFloat returns v as a C floating constant.
+func cFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// cString returns s as a C string literal.
+func cString(s string) string {
+	var out strings.Builder
+
+	out.WriteString("\"")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&out, "\\%03o", c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
@@ -0,0 +1,417 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/builtin"
+	"github.com/ysh86/b2c/semantic"
+	"github.com/ysh86/b2c/types"
+)
+
+// stringLiteral returns s as a b2c_str. The length is given explicitly
+// because s may have NUL bytes.
+func (g *Generator) stringLiteral(s string) string {
+	g.require("str")
+	return "b2c_lit(" + cString(s) + ", " + strconv.Itoa(len(s)) + ")"
+}
+
+// stringInfix returns the concatenation or the comparison of two strings.
+func (g *Generator) stringInfix(e *ast.InfixExpression, op string) string {
+	g.require("str")
+
+	left, right := g.expression(e.Left), g.expression(e.Right)
+	if op == "+" {
+		return "b2c_concat(" + left + ", " + right + ")"
+	}
+	return "(b2c_str_cmp(" + left + ", " + right + ") " + op + " 0)"
+}
+
+// stringCall returns the call of the builtin string function f.
+func (g *Generator) stringCall(f *builtin.Func, e *ast.CallExpression) string {
+	g.require("strfn")
+
+	args := e.Arguments
+	call := func(name string, a ...string) string {
+		return name + "(" + strings.Join(a, ", ") + ")"
+	}
+
+	switch f.Name {
+	case "LEN":
+		return "(" + g.expression(args[0]) + ").len"
+	case "ASC":
+		return call("b2c_asc", g.expression(args[0]))
+	case "CHR$":
+		return call("b2c_chr", g.intExpression(args[0]))
+	case "MID$":
+		n := "255" // to the end
+		if len(args) == 3 {
+			n = g.intExpression(args[2])
+		}
+		return call("b2c_mid", g.expression(args[0]), g.intExpression(args[1]), n)
+	case "LEFT$":
+		return call("b2c_left", g.expression(args[0]), g.intExpression(args[1]))
+	case "RIGHT$":
+		return call("b2c_right", g.expression(args[0]), g.intExpression(args[1]))
+	case "STR$":
+		switch semantic.TypeOf(args[0]) {
+		case types.Integer:
+			return call("b2c_str_int", g.expression(args[0]))
+		case types.Double:
+			return call("b2c_str_dbl", g.expression(args[0]))
+		}
+		return call("b2c_str_sng", g.expression(args[0]))
+	case "VAL":
+		return call("b2c_val", g.expression(args[0]))
+	case "INSTR":
+		if len(args) == 2 {
+			return call("b2c_instr", "1", g.expression(args[0]), g.expression(args[1]))
+		}
+		return call("b2c_instr", g.intExpression(args[0]), g.expression(args[1]), g.expression(args[2]))
+	case "STRING$":
+		if semantic.TypeOf(args[1]) == types.String {
+			return call("b2c_string", g.intExpression(args[0]), call("b2c_asc", g.expression(args[1])))
+		}
+		return call("b2c_string", g.intExpression(args[0]), g.intExpression(args[1]))
+	case "SPACE$":
+		return call("b2c_string", g.intExpression(args[0]), "' '")
+	case "HEX$":
+		return call("b2c_hex", g.expression(args[0]))
+	case "OCT$":
+		return call("b2c_oct", g.expression(args[0]))
+	case "MKI$":
+		g.require("mbf")
+		return call("b2c_mki", g.intExpression(args[0]))
+	case "MKS$", "MKD$":
+		g.require("mbf")
+		return call("b2c_"+strings.ToLower(strings.TrimSuffix(f.Name, "$")), g.expression(args[0]))
+	}
+
+	g.errorf(e, "unsupported function: %s", f.Name)
+	return ""
+}
+
+const strRuntime = `
+/*
+ * b2c_str is a string value: len bytes at p followed by a NUL. p may be
+ * NULL if len is 0. A variable owns its bytes, the other values are
+ * literals or temporaries.
+ */
+typedef struct {
+    char *p;
+    int len;
+} b2c_str;
+
+#define B2C_STR_MAX 255 /* the longest string */
+#ifndef B2C_STR_TEMPS
+#define B2C_STR_TEMPS 64
+#endif
+
+static char b2c_temps[B2C_STR_TEMPS][B2C_STR_MAX + 1];
+static int b2c_temp_next;
+
+static b2c_str b2c_lit(const char *p, int len)
+{
+    b2c_str s;
+
+    s.p = (char *)p;
+    s.len = len;
+    return s;
+}
+
+/*
+ * b2c_temp returns a temporary string of len bytes. It is valid until
+ * B2C_STR_TEMPS more temporaries are made, which is plenty for the
+ * expressions of a statement.
+ */
+static b2c_str b2c_temp(int len)
+{
+    b2c_str s;
+
+    if (len > B2C_STR_MAX) {
+        b2c_error(B2C_E_STRLEN);
+    }
+    s.p = b2c_temps[b2c_temp_next];
+    s.len = len;
+    s.p[len] = '\0';
+    b2c_temp_next = (b2c_temp_next + 1) % B2C_STR_TEMPS;
+    return s;
+}
+
+/* b2c_substr returns a temporary copy of n bytes of s from start. */
+static b2c_str b2c_substr(b2c_str s, int start, int n)
+{
+    b2c_str t = b2c_temp(n);
+
+    if (n > 0) {
+        memcpy(t.p, s.p + start, n);
+    }
+    return t;
+}
+
+/* b2c_copy returns a temporary copy of the C string p. */
+static b2c_str b2c_copy(const char *p)
+{
+    int n = (int)strlen(p);
+
+    return b2c_substr(b2c_lit(p, n), 0, n);
+}
+
+/* b2c_unfield, set by FIELD, makes v no longer a field of a record. */
+static void (*b2c_unfield)(b2c_str *v);
+
+/* b2c_str_set copies s into the variable v. */
+static void b2c_str_set(b2c_str *v, b2c_str s)
+{
+    char *p = malloc(s.len + 1);
+
+    if (p == NULL) {
+        b2c_error(B2C_E_MEMORY);
+    }
+    if (s.len > 0) {
+        memcpy(p, s.p, s.len);
+    }
+    p[s.len] = '\0';
+    free(v->p); /* s may be v itself */
+    v->p = p;
+    v->len = s.len;
+}
+
+/* b2c_str_assign is LET: v gets a copy of s and loses its field. */
+static void b2c_str_assign(b2c_str *v, b2c_str s)
+{
+    if (b2c_unfield != NULL) {
+        b2c_unfield(v);
+    }
+    b2c_str_set(v, s);
+}
+
+/* b2c_str_erase empties the n string variables at v. */
+static void b2c_str_erase(b2c_str *v, int n)
+{
+    int i;
+
+    for (i = 0; i < n; i++) {
+        free(v[i].p);
+        v[i].p = NULL;
+        v[i].len = 0;
+    }
+}
+
+static b2c_str b2c_concat(b2c_str a, b2c_str b)
+{
+    b2c_str s = b2c_temp(a.len + b.len);
+
+    if (a.len > 0) {
+        memcpy(s.p, a.p, a.len);
+    }
+    if (b.len > 0) {
+        memcpy(s.p + a.len, b.p, b.len);
+    }
+    return s;
+}
+
+/* b2c_str_cmp compares the bytes of a and b, then their lengths. */
+static int b2c_str_cmp(b2c_str a, b2c_str b)
+{
+    int n = a.len < b.len ? a.len : b.len;
+    int c = n > 0 ? memcmp(a.p, b.p, n) : 0;
+
+    if (c != 0) {
+        return c;
+    }
+    return a.len - b.len;
+}
+`
+
+const strfnRuntime = `
+static int b2c_asc(b2c_str s)
+{
+    if (s.len == 0) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    return (unsigned char)s.p[0];
+}
+
+static b2c_str b2c_chr(int c)
+{
+    b2c_str s;
+
+    if (c < 0 || c > 255) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    s = b2c_temp(1);
+    s.p[0] = (char)c;
+    return s;
+}
+
+static b2c_str b2c_left(b2c_str s, int n)
+{
+    if (n < 0 || n > B2C_STR_MAX) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    return b2c_substr(s, 0, n < s.len ? n : s.len);
+}
+
+static b2c_str b2c_right(b2c_str s, int n)
+{
+    if (n < 0 || n > B2C_STR_MAX) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    if (n > s.len) {
+        n = s.len;
+    }
+    return b2c_substr(s, s.len - n, n);
+}
+
+/* b2c_mid returns n bytes of s from start, which starts at 1. */
+static b2c_str b2c_mid(b2c_str s, int start, int n)
+{
+    if (start < 1 || start > B2C_STR_MAX || n < 0 || n > B2C_STR_MAX) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    if (start > s.len) {
+        return b2c_lit("", 0);
+    }
+    if (n > s.len - start + 1) {
+        n = s.len - start + 1;
+    }
+    return b2c_substr(s, start - 1, n);
+}
+
+/* STR$ is PRINT without the trailing space. */
+static b2c_str b2c_str_int(int n)
+{
+    char buf[16];
+
+    sprintf(buf, "% d", n);
+    return b2c_copy(buf);
+}
+
+static b2c_str b2c_str_sng(double x)
+{
+    char buf[40];
+
+    return b2c_copy(b2c_fmtnum(buf, x, 7, 'E'));
+}
+
+static b2c_str b2c_str_dbl(double x)
+{
+    char buf[40];
+
+    return b2c_copy(b2c_fmtnum(buf, x, 16, 'D'));
+}
+
+/* b2c_atof returns the value of the number at the start of s, or 0. */
+static double b2c_atof(const char *s)
+{
+    char buf[B2C_STR_MAX + 1];
+    int n = 0, digits = 0, k;
+
+    if (*s == '+' || *s == '-') {
+        buf[n++] = *s++;
+    }
+    for (; *s >= '0' && *s <= '9'; digits++) {
+        buf[n++] = *s++;
+    }
+    if (*s == '.') {
+        buf[n++] = *s++;
+        for (; *s >= '0' && *s <= '9'; digits++) {
+            buf[n++] = *s++;
+        }
+    }
+    if (digits > 0 && (*s == 'E' || *s == 'e' || *s == 'D' || *s == 'd')) {
+        k = n;
+        buf[n++] = 'e';
+        s++;
+        if (*s == '+' || *s == '-') {
+            buf[n++] = *s++;
+        }
+        if (!(*s >= '0' && *s <= '9')) {
+            n = k; /* not an exponent */
+        }
+        while (*s >= '0' && *s <= '9') {
+            buf[n++] = *s++;
+        }
+    }
+    buf[n] = '\0';
+
+    return digits > 0 ? strtod(buf, NULL) : 0;
+}
+
+/* b2c_val converts s to a number like VAL, ignoring the blanks. */
+static double b2c_val(b2c_str s)
+{
+    char buf[B2C_STR_MAX + 1];
+    int i, n = 0;
+
+    for (i = 0; i < s.len; i++) {
+        if (s.p[i] != ' ' && s.p[i] != '\t' && s.p[i] != '\n') {
+            buf[n++] = s.p[i];
+        }
+    }
+    buf[n] = '\0';
+
+    return b2c_atof(buf);
+}
+
+/* b2c_instr returns the position of t in s from start, or 0. */
+static int b2c_instr(int start, b2c_str s, b2c_str t)
+{
+    int i;
+
+    if (start < 1 || start > B2C_STR_MAX) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    if (start > s.len) {
+        return 0;
+    }
+    if (t.len == 0) {
+        return start;
+    }
+    for (i = start - 1; i + t.len <= s.len; i++) {
+        if (memcmp(s.p + i, t.p, t.len) == 0) {
+            return i + 1;
+        }
+    }
+    return 0;
+}
+
+/* b2c_string returns n copies of the character c. */
+static b2c_str b2c_string(int n, int c)
+{
+    b2c_str s;
+
+    if (n < 0 || n > B2C_STR_MAX || c < 0 || c > 255) {
+        b2c_error(B2C_E_ILLEGAL);
+    }
+    s = b2c_temp(n);
+    memset(s.p, c, n);
+    return s;
+}
+
+/* b2c_radix formats x as a 16-bit unsigned integer for HEX$ and OCT$. */
+static b2c_str b2c_radix(double x, const char *format)
+{
+    char buf[16];
+    long n;
+
+    if (x < -32768.5 || x >= 65535.5) {
+        b2c_error(B2C_E_OVERFLOW);
+    }
+    n = x >= 0 ? (long)(x + 0.5) : -(long)(-x + 0.5);
+    sprintf(buf, format, (unsigned)(n & 0xFFFF));
+    return b2c_copy(buf);
+}
+
+static b2c_str b2c_hex(double x)
+{
+    return b2c_radix(x, "%X");
+}
+
+static b2c_str b2c_oct(double x)
+{
+    return b2c_radix(x, "%o");
+}
+`
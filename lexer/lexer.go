@@ -26,6 +26,7 @@ SOFTWARE.
 
 import (
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/ysh86/b2c/token"
@@ -33,25 +34,42 @@ import (
 
 type Lexer struct {
 	reader  io.Reader
-	isFirst bool // Is it the first token?
-	ch      byte // current char under examination
-	peekCh  byte // char after current char
+	isFirst bool           // Is it the first token?
+	ch      byte           // current char under examination
+	peekCh  byte           // char after current char
+	pos     token.Position // position of ch
+	lineNo  int            // the last BASIC line number
 }
 
 func New(r io.Reader) *Lexer {
 	l := &Lexer{reader: r, isFirst: true}
 	l.readChar()
 	l.readChar()
+	l.pos = token.Position{Line: 1, Column: 1, Offset: 0}
 	return l
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	isNewLine := l.skipWhitespace()
 	isNewLine = (isNewLine || l.isFirst)
 	l.isFirst = false
 
+	pos := l.pos
+	tok := l.readToken(isNewLine)
+	if tok.Type == token.LINENO {
+		if n, err := strconv.Atoi(tok.Literal); err == nil {
+			l.lineNo = n
+		}
+	}
+	pos.LineNo = l.lineNo
+	tok.Pos = pos
+
+	return tok
+}
+
+func (l *Lexer) readToken(isNewLine bool) token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
@@ -61,29 +79,58 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.ASTERISK, l.ch)
 	case '/':
 		tok = newToken(token.SLASH, l.ch)
+	case '\\':
+		tok = newToken(token.BACKSLASH, l.ch)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
 	case '=':
-		tok = newToken(token.EQ, l.ch)
+		switch l.peekChar() {
+		case '<':
+			tok = l.readTwoCharToken(token.LT_EQ)
+		case '>':
+			tok = l.readTwoCharToken(token.GT_EQ)
+		default:
+			tok = newToken(token.EQ, l.ch)
+		}
 	case '<':
-		if l.peekChar() == '>' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.NOT_EQ, Literal: literal}
-		} else {
+		switch l.peekChar() {
+		case '>':
+			tok = l.readTwoCharToken(token.NOT_EQ)
+		case '=':
+			tok = l.readTwoCharToken(token.LT_EQ)
+		default:
 			tok = newToken(token.LT, l.ch)
 		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '<':
+			tok = l.readTwoCharToken(token.NOT_EQ)
+		case '=':
+			tok = l.readTwoCharToken(token.GT_EQ)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '#':
+		tok = newToken(token.HASH, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
+	case '?':
+		tok = newToken(token.PRINT, l.ch) // shorthand for PRINT
+	case '&':
+		if lit := l.readRadixNumber(); lit != "" {
+			tok.Type = token.NUM
+			tok.Literal = lit
+			return tok
+		}
+		tok = newToken(token.ILLEGAL, l.ch)
 	case '\'':
 		l.readChar()
 		tok.Type = token.REM
@@ -109,7 +156,7 @@ func (l *Lexer) NextToken() token.Token {
 				tok.Literal = l.readData()
 			}
 			return tok
-		} else if isDigit(l.ch) {
+		} else if isDigit(l.ch) || (l.ch == '.' && isDigit(l.peekChar())) {
 			if isNewLine {
 				tok.Type = token.LINENO
 				tok.Literal = l.readInteger()
@@ -127,6 +174,14 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// readTwoCharToken reads an operator of two characters, such as <=. The
+// literal is kept as written, so => is a GT_EQ token with the literal =>.
+func (l *Lexer) readTwoCharToken(t token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: t, Literal: string(ch) + string(l.ch)}
+}
+
 func (l *Lexer) skipWhitespace() (isNewLine bool) {
 	isNewLine = false
 	for isSpace(l.ch) || isCRLF(l.ch) {
@@ -143,6 +198,14 @@ func (l *Lexer) skipWhitespace() (isNewLine bool) {
 func (l *Lexer) readChar() {
 	var p [1]byte
 
+	if l.ch == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+	} else {
+		l.pos.Column++
+	}
+	l.pos.Offset++
+
 	_, err := l.reader.Read(p[:])
 	if err != nil {
 		l.ch = l.peekCh
@@ -162,9 +225,12 @@ func (l *Lexer) readData() string {
 	for isSpace(l.ch) {
 		l.readChar()
 	}
-	// TODO: 数値か文字列("" は省略可)
 	var out strings.Builder
-	for l.ch != ':' && !isCRLF(l.ch) && l.ch != 0 { // TODO: , でつなげられる
+	quoted := false
+	for (quoted || l.ch != ':') && !isCRLF(l.ch) && l.ch != 0 {
+		if l.ch == '"' {
+			quoted = !quoted // DATA "A:B"
+		}
 		out.WriteByte(l.ch)
 		l.readChar()
 	}
@@ -186,9 +252,13 @@ func (l *Lexer) readIdentifier() string {
 		out.WriteByte(l.ch)
 		l.readChar()
 	}
-	if l.ch == '$' {
-		out.WriteByte(l.ch)
-		l.readChar()
+	if isTypeSuffix(l.ch) {
+		// a keyword followed by '#' is not a variable: PRINT#1
+		base := out.String()
+		if token.LookupIdent(base) == token.IDENT || token.LookupIdent(base+string(l.ch)) != token.IDENT {
+			out.WriteByte(l.ch)
+			l.readChar()
+		}
 	}
 	return out.String()
 }
@@ -208,9 +278,72 @@ func (l *Lexer) readNumber() string {
 		out.WriteByte(l.ch)
 		l.readChar()
 	}
+
+	// exponent: 1.5E-3, 1D2
+	if isExponent(l.ch) && (isDigit(l.peekChar()) || l.peekChar() == '+' || l.peekChar() == '-') {
+		out.WriteByte(l.ch)
+		l.readChar()
+		out.WriteByte(l.ch)
+		l.readChar()
+		for isDigit(l.ch) {
+			out.WriteByte(l.ch)
+			l.readChar()
+		}
+	}
+
+	// type suffix: 10%, 3.5!, 2#
+	if isTypeSuffix(l.ch) && l.ch != '$' {
+		out.WriteByte(l.ch)
+		l.readChar()
+	}
+
+	return out.String()
+}
+
+// readRadixNumber reads a number like &H7F, &O17 (or &17) and &B1010. It
+// returns "" and reads nothing if the '&' does not start a number.
+func (l *Lexer) readRadixNumber() string {
+	var isRadixDigit func(byte) bool
+	prefix := "&"
+
+	switch l.peekChar() {
+	case 'H', 'h':
+		isRadixDigit = isHexDigit
+	case 'O', 'o':
+		isRadixDigit = isOctalDigit
+	case 'B', 'b':
+		isRadixDigit = isBinaryDigit
+	default:
+		if !isOctalDigit(l.peekChar()) {
+			return ""
+		}
+		isRadixDigit = isOctalDigit
+		prefix = "&O"
+	}
+
+	l.readChar() // '&'
+	if prefix == "&" {
+		prefix += strings.ToUpper(string(l.ch))
+		l.readChar()
+	}
+
+	var out strings.Builder
+	out.WriteString(prefix)
+	for isRadixDigit(l.ch) {
+		out.WriteByte(l.ch)
+		l.readChar()
+	}
 	return out.String()
 }
 
+func isExponent(ch byte) bool {
+	return ch == 'E' || ch == 'e' || ch == 'D' || ch == 'd'
+}
+
+func isTypeSuffix(ch byte) bool {
+	return ch == '$' || ch == '%' || ch == '!' || ch == '#'
+}
+
 func isSpace(ch byte) bool {
 	return ch == ' ' || ch == '\t'
 }
@@ -227,6 +360,18 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isOctalDigit(ch byte) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+func isBinaryDigit(ch byte) bool {
+	return ch == '0' || ch == '1'
+}
+
 func newToken(tokenType token.TokenType, ch byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
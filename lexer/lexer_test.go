@@ -82,9 +82,9 @@ REM this is test
 		{token.ILLEGAL, "\n"},
 		{token.NUM, "4"},
 		{token.LINENO, "10"},
-		{token.IDENT, "CLEAR"},
+		{token.CLEAR, "CLEAR"},
 		{token.COLON, ":"},
-		{token.IDENT, "RANDOMIZE"},
+		{token.RANDOMIZE, "RANDOMIZE"},
 		{token.COLON, ":"},
 		{token.DIM, "DIM"},
 		{token.IDENT, "ADD"},
@@ -133,7 +133,7 @@ REM this is test
 		{token.NOT_EQ, "<>"},
 		{token.NUM, "9"},
 		{token.LINENO, "80"},
-		{token.IDENT, "PRINT"},
+		{token.PRINT, "PRINT"},
 		{token.STRING, "foo"},
 		{token.SEMICOLON, ";"},
 		{token.IDENT, "A$"},
@@ -204,3 +204,217 @@ REM this is test
 		}
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := "10 A=1\n20  PRINT \"X\";B$\r\n*L:GOTO 10\n"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{"10", token.Position{LineNo: 10, Line: 1, Column: 1, Offset: 0}},
+		{"A", token.Position{LineNo: 10, Line: 1, Column: 4, Offset: 3}},
+		{"=", token.Position{LineNo: 10, Line: 1, Column: 5, Offset: 4}},
+		{"1", token.Position{LineNo: 10, Line: 1, Column: 6, Offset: 5}},
+		{"20", token.Position{LineNo: 20, Line: 2, Column: 1, Offset: 7}},
+		{"PRINT", token.Position{LineNo: 20, Line: 2, Column: 5, Offset: 11}},
+		{"X", token.Position{LineNo: 20, Line: 2, Column: 11, Offset: 17}},
+		{";", token.Position{LineNo: 20, Line: 2, Column: 14, Offset: 20}},
+		{"B$", token.Position{LineNo: 20, Line: 2, Column: 15, Offset: 21}},
+		{"*", token.Position{LineNo: 20, Line: 3, Column: 1, Offset: 25}},
+		{"L", token.Position{LineNo: 20, Line: 3, Column: 2, Offset: 26}},
+		{":", token.Position{LineNo: 20, Line: 3, Column: 3, Offset: 27}},
+		{"GOTO", token.Position{LineNo: 20, Line: 3, Column: 4, Offset: 28}},
+		{"10", token.Position{LineNo: 20, Line: 3, Column: 9, Offset: 33}},
+		{"", token.Position{LineNo: 20, Line: 4, Column: 1, Offset: 36}},
+	}
+
+	l := New(bytes.NewBufferString(input))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestTokenLineNo(t *testing.T) {
+	input := "PRINT 1\n10 A=1\nB=2\n*L\n20 END"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLineNo  int
+	}{
+		{"PRINT", 0},
+		{"1", 0},
+		{"10", 10},
+		{"A", 10},
+		{"=", 10},
+		{"1", 10},
+		{"B", 10},
+		{"=", 10},
+		{"2", 10},
+		{"*", 10},
+		{"L", 10},
+		{"20", 20},
+		{"END", 20},
+	}
+
+	l := New(bytes.NewBufferString(input))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.LineNo != tt.expectedLineNo {
+			t.Fatalf("tests[%d] - line number wrong. expected=%d, got=%d",
+				i, tt.expectedLineNo, tok.Pos.LineNo)
+		}
+	}
+}
+
+func TestTypeSuffix(t *testing.T) {
+	input := "A$ B% C! D# CHR$ GOTO# E1$"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "A$"},
+		{token.IDENT, "B%"},
+		{token.IDENT, "C!"},
+		{token.IDENT, "D#"},
+		{token.CHR_D, "CHR$"},
+		{token.GOTO, "GOTO"},
+		{token.HASH, "#"},
+		{token.IDENT, "E1$"},
+		{token.EOF, ""},
+	}
+
+	l := New(bytes.NewBufferString(input))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := "<= =< >= => <> >< < > = ^ \\ NOT A XOR B EQV C IMP D MOD E MODE"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LT_EQ, "<="},
+		{token.LT_EQ, "=<"},
+		{token.GT_EQ, ">="},
+		{token.GT_EQ, "=>"},
+		{token.NOT_EQ, "<>"},
+		{token.NOT_EQ, "><"},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.EQ, "="},
+		{token.CARET, "^"},
+		{token.BACKSLASH, "\\"},
+		{token.NOT, "NOT"},
+		{token.IDENT, "A"},
+		{token.XOR, "XOR"},
+		{token.IDENT, "B"},
+		{token.EQV, "EQV"},
+		{token.IDENT, "C"},
+		{token.IMP, "IMP"},
+		{token.IDENT, "D"},
+		{token.MOD, "MOD"},
+		{token.IDENT, "E"},
+		{token.IDENT, "MODE"},
+		{token.EOF, ""},
+	}
+
+	l := New(bytes.NewBufferString(input))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	input := "A=1.5E-3+1D2*.5-2E+10/3e4 ELSE 1ELSE 10% 3.5! 2# &H7f &o17 &17 &B101 & X"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "A"},
+		{token.EQ, "="},
+		{token.NUM, "1.5E-3"},
+		{token.PLUS, "+"},
+		{token.NUM, "1D2"},
+		{token.ASTERISK, "*"},
+		{token.NUM, ".5"},
+		{token.MINUS, "-"},
+		{token.NUM, "2E+10"},
+		{token.SLASH, "/"},
+		{token.NUM, "3e4"},
+		{token.ELSE, "ELSE"},
+		{token.NUM, "1"},
+		{token.ELSE, "ELSE"},
+		{token.NUM, "10%"},
+		{token.NUM, "3.5!"},
+		{token.NUM, "2#"},
+		{token.NUM, "&H7f"},
+		{token.NUM, "&O17"},
+		{token.NUM, "&O17"},
+		{token.NUM, "&B101"},
+		{token.ILLEGAL, "&"},
+		{token.IDENT, "X"},
+		{token.EOF, ""},
+	}
+
+	l := New(bytes.NewBufferString(input))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
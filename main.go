@@ -8,28 +8,72 @@ import (
 	"io"
 	"os"
 
+	"github.com/ysh86/b2c/codegen"
 	"github.com/ysh86/b2c/lexer"
 	"github.com/ysh86/b2c/parser"
 )
 
-func parse(r io.Reader, w io.Writer) error {
+var gosubDepth int
+
+// parse transpiles r to w. The diagnostics of the parser are returned
+// as a parser.ErrorList, which stops the transpilation only if it has
+// errors rather than just warnings.
+func parse(r io.Reader, w io.Writer, isFragment bool) error {
 	l := lexer.New(r)
 	p := parser.New(l)
 
-	p.ParseProgram(func(s string, isErrors bool) {
-		if s != "" {
-			io.WriteString(w, s)
-			io.WriteString(w, "\n")
-		}
-		if isErrors {
-			p.PrintErrors(w)
-		}
-	})
+	program, err := p.ParseProgram()
+	warnings, ok := err.(parser.ErrorList)
+	if err != nil && (!ok || warnings.HasErrors()) {
+		return err
+	}
+
+	g := codegen.New()
+	g.GosubDepth = gosubDepth
+
+	if isFragment {
+		err = g.GenerateFragment(w, program)
+	} else {
+		err = g.Generate(w, program)
+	}
+	if e, ok := err.(*codegen.Error); ok && len(warnings) > 0 {
+		return append(warnings, &parser.ParseError{Pos: e.Pos, Msg: e.Msg, Severity: parser.SeverityError})
+	}
+	if err != nil {
+		return err
+	}
+	return warnings.Err()
+}
 
+// isFatal reports whether err stops the program, rather than being
+// only warnings.
+func isFatal(err error) bool {
+	errs, ok := err.(parser.ErrorList)
+	return !ok || errs.HasErrors()
+}
+
+// printErrors writes the diagnostics of err to w. It returns an error
+// if they cannot be written.
+func printErrors(w io.Writer, filename string, err error, isJSON bool) error {
+	var errs parser.ErrorList
+	switch e := err.(type) {
+	case parser.ErrorList:
+		errs = e
+	case *codegen.Error:
+		errs = parser.ErrorList{{Pos: e.Pos, Msg: e.Msg, Severity: parser.SeverityError}}
+	default:
+		_, err := fmt.Fprintf(w, "%s: %v\n", filename, err)
+		return err
+	}
+
+	if isJSON {
+		return errs.PrintJSON(w, filename)
+	}
+	errs.Print(w, filename)
 	return nil
 }
 
-func repl(r io.Reader, w io.Writer) {
+func repl(r io.Reader, w io.Writer, isJSON bool) {
 	scanner := bufio.NewScanner(r)
 
 	fmt.Println("b2c: a BASIC to C transpiler in golang")
@@ -41,19 +85,26 @@ func repl(r io.Reader, w io.Writer) {
 		}
 
 		line := bytes.NewBufferString(scanner.Text())
-		parse(line, w)
+		if err := parse(line, w, true); err != nil {
+			if perr := printErrors(os.Stderr, "<stdin>", err, isJSON); perr != nil {
+				fmt.Fprintf(os.Stderr, "<stdin>: %v\n", perr)
+			}
+		}
 	}
 }
 
 func main() {
 	var isTranspiler bool
+	var isJSON bool
 	var inFileName string
 
 	flag.BoolVar(&isTranspiler, "c", false, "do transpile")
+	flag.BoolVar(&isJSON, "json", false, "print diagnostics as JSON")
+	flag.IntVar(&gosubDepth, "gosub-depth", codegen.DefaultGosubDepth, "size of the GOSUB return stack")
 	flag.Parse()
 
-	if len(os.Args) > 2 {
-		inFileName = os.Args[2]
+	if flag.NArg() > 0 {
+		inFileName = flag.Arg(0)
 	}
 
 	if isTranspiler {
@@ -65,8 +116,16 @@ func main() {
 
 		reader := bufio.NewReader(file)
 
-		parse(reader, os.Stdout)
+		if err := parse(reader, os.Stdout, false); err != nil {
+			if perr := printErrors(os.Stderr, inFileName, err, isJSON); perr != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", inFileName, perr)
+				os.Exit(1)
+			}
+			if isFatal(err) {
+				os.Exit(1)
+			}
+		}
 	} else {
-		repl(os.Stdin, os.Stdout)
+		repl(os.Stdin, os.Stdout, isJSON)
 	}
 }
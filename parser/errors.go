@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ysh86/b2c/token"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// ParseError is a diagnostic reported by the parser.
+type ParseError struct {
+	Pos      token.Position
+	Expected token.TokenType // the expected token, if any
+	Found    token.Token     // the offending token
+	Msg      string
+	Severity Severity
+}
+
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// ErrorList is a list of *ParseError in the order they were reported.
+type ErrorList []*ParseError
+
+func (el ErrorList) Error() string {
+	switch len(el) {
+	case 0:
+		return "no errors"
+	case 1:
+		return el[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", el[0], len(el)-1)
+}
+
+// Err returns an error equivalent to this list, or nil if the list is empty.
+func (el ErrorList) Err() error {
+	if len(el) == 0 {
+		return nil
+	}
+	return el
+}
+
+// HasErrors reports whether the list contains any SeverityError entries.
+func (el ErrorList) HasErrors() bool {
+	for _, e := range el {
+		if e.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the list as "file:line:col: severity: message" lines.
+func (el ErrorList) Print(w io.Writer, filename string) {
+	for _, e := range el {
+		fmt.Fprintf(w, "%s:%s: %s: %s\n", filename, e.Pos, e.Severity, e.Msg)
+	}
+}
+
+type jsonError struct {
+	File     string `json:"file"`
+	LineNo   int    `json:"lineno"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Expected string `json:"expected,omitempty"`
+	Found    string `json:"found,omitempty"`
+}
+
+// PrintJSON writes the list as a JSON array.
+func (el ErrorList) PrintJSON(w io.Writer, filename string) error {
+	out := make([]jsonError, 0, len(el))
+	for _, e := range el {
+		out = append(out, jsonError{
+			File:     filename,
+			LineNo:   e.Pos.LineNo,
+			Line:     e.Pos.Line,
+			Column:   e.Pos.Column,
+			Offset:   e.Pos.Offset,
+			Severity: e.Severity.String(),
+			Message:  e.Msg,
+			Expected: string(e.Expected),
+			Found:    string(e.Found.Type),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ysh86/b2c/token"
+)
+
+var (
+	errSyntax = &ParseError{
+		Pos:      token.Position{LineNo: 10, Line: 1, Column: 6, Offset: 5},
+		Expected: token.RPAREN,
+		Found:    token.Token{Type: token.EOF, Pos: token.Position{LineNo: 10, Line: 1, Column: 6, Offset: 5}},
+		Msg:      "expected next token to be ), got EOF instead",
+		Severity: SeverityError,
+	}
+	errUnused = &ParseError{
+		Pos:      token.Position{LineNo: 20, Line: 2, Column: 4, Offset: 14},
+		Msg:      "unused label *L",
+		Severity: SeverityWarning,
+	}
+	errNoPos = &ParseError{Msg: "no program"}
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		err      *ParseError
+		expected string
+	}{
+		{errSyntax, "1:6: expected next token to be ), got EOF instead"},
+		{errUnused, "2:4: unused label *L"},
+		{errNoPos, "-: no program"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("Error() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	tests := []struct {
+		list      ErrorList
+		expected  string
+		hasErrors bool
+	}{
+		{nil, "no errors", false},
+		{ErrorList{errUnused}, "2:4: unused label *L", false},
+		{ErrorList{errSyntax}, "1:6: expected next token to be ), got EOF instead", true},
+		{ErrorList{errUnused, errSyntax, errNoPos}, "2:4: unused label *L (and 2 more errors)", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.list.Error(); got != tt.expected {
+			t.Errorf("Error() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+		if got := tt.list.HasErrors(); got != tt.hasErrors {
+			t.Errorf("HasErrors() of %q wrong. expected=%t, got=%t", tt.expected, tt.hasErrors, got)
+		}
+
+		// Err is nil only for no diagnostics, even warnings
+		if err := tt.list.Err(); (err == nil) != (len(tt.list) == 0) {
+			t.Errorf("Err() of %q wrong. got=%v", tt.expected, err)
+		}
+	}
+}
+
+func TestErrorListPrint(t *testing.T) {
+	tests := []struct {
+		list     ErrorList
+		expected string
+	}{
+		{nil, ""},
+		{
+			ErrorList{errUnused, errSyntax},
+			"a.bas:2:4: warning: unused label *L\n" +
+				"a.bas:1:6: error: expected next token to be ), got EOF instead\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		tt.list.Print(&out, "a.bas")
+		if got := out.String(); got != tt.expected {
+			t.Errorf("Print() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestErrorListPrintJSON(t *testing.T) {
+	tests := []struct {
+		list     ErrorList
+		expected string
+	}{
+		{nil, "[]\n"},
+		{
+			ErrorList{errSyntax, errUnused},
+			`[
+  {
+    "file": "a.bas",
+    "lineno": 10,
+    "line": 1,
+    "column": 6,
+    "offset": 5,
+    "severity": "error",
+    "message": "expected next token to be ), got EOF instead",
+    "expected": ")",
+    "found": "EOF"
+  },
+  {
+    "file": "a.bas",
+    "lineno": 20,
+    "line": 2,
+    "column": 4,
+    "offset": 14,
+    "severity": "warning",
+    "message": "unused label *L"
+  }
+]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := tt.list.PrintJSON(&out, "a.bas"); err != nil {
+			t.Fatalf("PrintJSON() error: %v", err)
+		}
+		if got := out.String(); got != tt.expected {
+			t.Errorf("PrintJSON() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
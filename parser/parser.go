@@ -26,39 +26,55 @@ SOFTWARE.
 
 import (
 	"fmt"
-	"io"
 	"strconv"
+	"strings"
 
 	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/builtin"
 	"github.com/ysh86/b2c/lexer"
 	"github.com/ysh86/b2c/token"
+	"github.com/ysh86/b2c/types"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	IMPLY       // IMP
+	EQUIVALENCE // EQV
+	LOGICXOR    // XOR
 	LOGICOR     // OR
 	LOGICAND    // AND
-	EQUALS      // = (NOT assignment)
-	LESSGREATER // > or <
+	LOGICNOT    // NOT X
+	RELATION    // = (NOT assignment), <>, <, >, <= or >=
 	SUM         // + or -
+	MODULO      // MOD
+	INTDIV      // \
 	PRODUCT     // / or *
 	PREFIX      // -X, LEN etc.
+	POWER       // ^
 	CALL        // myFunction(X) or (group)
 )
 
 var precedences = map[token.TokenType]int{
-	token.OR:       LOGICOR,
-	token.AND:      LOGICAND,
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.IMP:       IMPLY,
+	token.EQV:       EQUIVALENCE,
+	token.XOR:       LOGICXOR,
+	token.OR:        LOGICOR,
+	token.AND:       LOGICAND,
+	token.EQ:        RELATION,
+	token.NOT_EQ:    RELATION,
+	token.LT:        RELATION,
+	token.GT:        RELATION,
+	token.LT_EQ:     RELATION,
+	token.GT_EQ:     RELATION,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.MOD:       MODULO,
+	token.BACKSLASH: INTDIV,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.CARET:     POWER,
+	token.LPAREN:    CALL,
 }
 
 type (
@@ -68,7 +84,7 @@ type (
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors ErrorList
 
 	curToken  token.Token
 	peekToken token.Token
@@ -77,22 +93,33 @@ type Parser struct {
 	infixParseFns  map[token.TokenType]infixParseFn
 
 	dimVars map[string]*token.Token
+	doDepth int                // the number of DO loops around the current statement
+	loops   []*loop            // the loops around the current statement
+	ifs     []*ast.IfStatement // the IFs around the current statement
+}
+
+// loop is a loop being parsed.
+type loop struct {
+	start token.Token        // FOR, WHILE or DO
+	ifs   int                // the number of IFs around the loop
+	next  *ast.NextStatement // the NEXT in an IF that ends the FOR
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: ErrorList{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
-	p.registerPrefix(token.NUM, p.parseIntegerLiteral) // TODO: float
+	p.registerPrefix(token.NUM, p.parseNumberLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
-	p.registerPrefix(token.LEN, p.parsePrefixExpression)
-	p.registerPrefix(token.ASC, p.parsePrefixExpression)
-	p.registerPrefix(token.CHR_D, p.parsePrefixExpression)
+	p.registerPrefix(token.NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.LEN, p.parseKeywordCall)
+	p.registerPrefix(token.ASC, p.parseKeywordCall)
+	p.registerPrefix(token.CHR_D, p.parseKeywordCall)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -104,8 +131,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.XOR, p.parseInfixExpression)
+	p.registerInfix(token.EQV, p.parseInfixExpression)
+	p.registerInfix(token.IMP, p.parseInfixExpression)
+	p.registerInfix(token.MOD, p.parseInfixExpression)
+	p.registerInfix(token.BACKSLASH, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 
@@ -141,47 +176,54 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
-func (p *Parser) PrintErrors(w io.Writer) {
-	io.WriteString(w, "// ERR: ========== parser ==========\n")
-	for _, msg := range p.errors {
-		io.WriteString(w, "//  "+msg+"\n")
-	}
-	io.WriteString(w, "\n")
-	p.errors = nil // clear messages
+func (p *Parser) Errors() ErrorList {
+	return p.errors
+}
+
+func (p *Parser) errorf(tok token.Token, format string, a ...interface{}) {
+	p.errors = append(p.errors, &ParseError{
+		Pos:      tok.Pos,
+		Found:    tok,
+		Msg:      fmt.Sprintf(format, a...),
+		Severity: SeverityError,
+	})
 }
 
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errors = append(p.errors, &ParseError{
+		Pos:      p.peekToken.Pos,
+		Expected: t,
+		Found:    p.peekToken,
+		Msg:      msg,
+		Severity: SeverityError,
+	})
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorf(p.curToken, "no prefix parse function for %s found", t)
 }
 
 // ------------------------------------------------------------
 // Program
 // ------------------------------------------------------------
 
-func (p *Parser) ParseProgram(callback func(s string, isErrors bool)) *ast.Program {
+// ParseProgram parses the whole input. The returned error is an ErrorList
+// when the parser reported any diagnostics.
+func (p *Parser) ParseProgram() (*ast.Program, error) {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		isErrors := len(p.errors) > 0
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
-			callback(stmt.String(), isErrors)
-		} else {
-			callback("", isErrors)
 		}
 		p.nextToken()
 	}
 
-	return program
+	return program, p.errors.Err()
 }
 
 // ------------------------------------------------------------
@@ -211,16 +253,67 @@ func (p *Parser) parseStatement() ast.Statement {
 			return s
 		}
 		return nil
+	case token.DEF:
+		if s := p.parseDefFnStatement(); s != nil {
+			return s
+		}
+		return nil
 	case token.IF:
 		if s := p.parseIfStatement(); s != nil {
 			return s
 		}
 		return nil
+	case token.END:
+		if p.peekTokenIs(token.IF) {
+			p.errorf(p.curToken, "END IF without IF")
+			p.nextToken()
+			return nil
+		}
+		if s := p.parseEndStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.STOP:
+		if s := p.parseStopStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.CLEAR:
+		if s := p.parseClearStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.SWAP:
+		if s := p.parseSwapStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.ERASE:
+		if s := p.parseEraseStatement(); s != nil {
+			return s
+		}
+		return nil
 	case token.ON:
+		if p.peekTokenIs(token.ERROR) {
+			if s := p.parseOnErrorStatement(); s != nil {
+				return s
+			}
+			return nil
+		}
 		if s := p.parseOnStatement(); s != nil {
 			return s
 		}
 		return nil
+	case token.RESUME:
+		if s := p.parseResumeStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.ERROR:
+		if s := p.parseErrorStatement(); s != nil {
+			return s
+		}
+		return nil
 	case token.GOTO:
 		if s := p.parseGotoStatement(); s != nil {
 			return s
@@ -231,6 +324,26 @@ func (p *Parser) parseStatement() ast.Statement {
 			return s
 		}
 		return nil
+	case token.DATA:
+		if s := p.parseDataStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.READ:
+		if s := p.parseReadStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.RESTORE:
+		if s := p.parseRestoreStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.RANDOMIZE:
+		if s := p.parseRandomizeStatement(); s != nil {
+			return s
+		}
+		return nil
 	case token.RETURN:
 		if s := p.parseReturnStatement(); s != nil {
 			return s
@@ -241,13 +354,91 @@ func (p *Parser) parseStatement() ast.Statement {
 			return s
 		}
 		return nil
-	case token.NEXT:
-		msg := fmt.Sprintf("invalid statement: %s", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+	case token.WHILE:
+		if s := p.parseWhileStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.DO:
+		if s := p.parseDoStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.EXIT:
+		if s := p.parseExitStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.NEXT, token.WEND, token.LOOP:
+		if p.curTokenIs(token.NEXT) && p.nextEndsLoop() {
+			if s := p.parseNextStatement(); s != nil {
+				return s
+			}
+			return nil
+		}
+		p.errorf(p.curToken, "%s without %s", p.curToken.Literal, loopStarts[p.curToken.Type])
+		for !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) {
+			p.nextToken()
+		}
 		if p.peekTokenIs(token.COLON) {
 			p.nextToken()
 		}
 		return nil
+	case token.PRINT:
+		if s := p.parsePrintStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.INPUT:
+		if s := p.parseInputStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.LINE:
+		if s := p.parseLineInputStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.WRITE:
+		if s := p.parseWriteStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.OPEN:
+		if s := p.parseOpenStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.CLOSE:
+		if s := p.parseCloseStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.KILL:
+		if s := p.parseKillStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.NAME:
+		if s := p.parseNameStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.FIELD:
+		if s := p.parseFieldStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.GET, token.PUT:
+		if s := p.parseRecordStatement(); s != nil {
+			return s
+		}
+		return nil
+	case token.LSET, token.RSET:
+		if s := p.parseSetStatement(); s != nil {
+			return s
+		}
+		return nil
 	case token.IDENT:
 		if p.peekToken.Type == token.EQ {
 			if s := p.parseLetStatement(); s != nil {
@@ -264,8 +455,7 @@ func (p *Parser) parseStatement() ast.Statement {
 		}
 		return nil
 	default:
-		msg := fmt.Sprintf("got invalid token: %s", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "got invalid token: %s", p.curToken.Literal)
 		return nil
 	}
 }
@@ -274,24 +464,9 @@ func (p *Parser) parseLineNoStatement() *ast.LineNoStatement {
 	stmt := &ast.LineNoStatement{Token: p.curToken}
 
 	l := p.curToken.Literal
-	t := token.Token{Type: token.IDENT, Literal: l}
+	t := token.Token{Type: token.IDENT, Literal: l, Pos: p.curToken.Pos}
 	stmt.Name = &ast.Identifier{Token: t, Value: l}
 
-	if p.peekTokenIs(token.DATA) {
-		p.nextToken()
-
-		if s := p.parseDataStatement(); s != nil {
-			s.Name = &ast.Identifier{Token: t, Value: l}
-			stmt.Data = s
-		} else {
-			return nil
-		}
-
-		if p.peekTokenIs(token.COLON) {
-			p.nextToken()
-		}
-	}
-
 	return stmt
 }
 
@@ -398,9 +573,84 @@ func (p *Parser) parseDimParameters() []*ast.IntegerLiteral {
 	return integers
 }
 
+func (p *Parser) parseDefFnStatement() *ast.DefFnStatement {
+	stmt := &ast.DefFnStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	name := p.parseFnName()
+	if name == nil {
+		return nil
+	}
+
+	stmt.Name = name
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+
+		for {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			stmt.Parameters = append(stmt.Parameters, param)
+
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.EQ) {
+		return nil
+	}
+
+	p.nextToken()
+
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+
+	stmt.Value = value
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseFnName parses the name of a user function, written FNA or FN A.
+func (p *Parser) parseFnName() *ast.Identifier {
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if name.Value == "FN" && p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		name.Value += p.curToken.Literal
+	}
+
+	if !name.IsFn() {
+		p.errorf(name.Token, "expected a function name FNx, got %s instead", name.Value)
+		return nil
+	}
+
+	return name
+}
+
 func (p *Parser) parseIfStatement() *ast.IfStatement {
 	stmt := &ast.IfStatement{Token: p.curToken}
 
+	p.ifs = append(p.ifs, stmt)
+	defer func() { p.ifs = p.ifs[:len(p.ifs)-1] }()
+
 	p.nextToken()
 
 	cond := p.parseExpression(LOWEST)
@@ -410,49 +660,120 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 
 	stmt.Condition = cond
 
-	if !p.expectPeek(token.THEN) {
+	switch {
+	case p.peekTokenIs(token.GOTO):
+		// IF A GOTO 10 is IF A THEN GOTO 10
+		p.nextToken()
+	case !p.expectPeek(token.THEN):
 		return nil
+	case p.peekTokenIs(token.EOF) || p.peekToken.Pos.Line > p.curToken.Pos.Line:
+		// nothing follows THEN on its line
+		return p.parseBlockIf(stmt)
+	case p.peekTokenIs(token.ELSE):
+		// IF A THEN ELSE B=1 has no consequence
+	default:
+		p.jumpOrNext()
+	}
+
+	stmts := []ast.Statement{}
+	if !p.curTokenIs(token.THEN) {
+		stmts = p.parseStatements(token.ELSE, true)
+	}
+
+	stmt.Consequence = stmts
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+
+		stmts := []ast.Statement{}
+		if !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) {
+			p.jumpOrNext()
+			stmts = p.parseStatements(token.LINENO, true)
+		}
+
+		stmt.Alternative = stmts
 	}
 
+	return stmt
+}
+
+// jumpOrNext moves to the statement after THEN or ELSE. A line number or
+// a label alone is a jump, so THEN or ELSE is overwritten to be GOTO.
+func (p *Parser) jumpOrNext() {
 	if p.peekTokenIs(token.ASTERISK) || p.peekTokenIs(token.NUM) {
-		// overwrite the 'THEN' token
 		p.curToken.Type = token.GOTO
 		p.curToken.Literal = token.GOTO
-	} else {
-		p.nextToken()
+		return
 	}
+	p.nextToken()
+}
 
-	stmts := p.parseStatements(token.ELSE, true)
-	if stmts == nil {
-		// TODO: error message
-		return nil
-	}
+// parseBlockIf parses the lines after THEN of a block IF, up to END IF.
+// An ELSEIF is parsed as a block IF in the alternative.
+func (p *Parser) parseBlockIf(stmt *ast.IfStatement) *ast.IfStatement {
+	stmt.Block = true
+	stmt.Consequence = p.parseIfBlock()
 
-	stmt.Consequence = stmts
+	switch {
+	case p.curTokenIs(token.ELSEIF):
+		elseIf := &ast.IfStatement{Token: p.curToken}
 
-	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
-		if p.peekTokenIs(token.ASTERISK) || p.peekTokenIs(token.NUM) {
-			// overwrite the 'ELSE' token
-			p.curToken.Type = token.GOTO
-			p.curToken.Literal = token.GOTO
-		} else {
-			p.nextToken()
+		elseIf.Condition = p.parseExpression(LOWEST)
+		if elseIf.Condition == nil || !p.expectPeek(token.THEN) {
+			return nil
 		}
 
-		stmts := p.parseStatements(token.LINENO, true)
-		if stmts == nil {
-			// TODO: error message
+		if p.parseBlockIf(elseIf) == nil {
 			return nil
 		}
+		stmt.Alternative = []ast.Statement{elseIf}
 
-		stmt.Alternative = stmts
+		return stmt
+	case p.curTokenIs(token.ELSE):
+		stmt.Alternative = p.parseIfBlock()
+	}
+
+	switch {
+	case p.curTokenIs(token.EOF):
+		p.errorf(stmt.Token, "%s without END IF", stmt.Token.Literal)
+		return nil
+	case !p.curTokenIs(token.END):
+		p.errorf(p.curToken, "unexpected %s in block IF", p.curToken.Literal)
+		return nil
+	}
+
+	p.nextToken() // IF
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
 	}
 
 	return stmt
 }
 
+// parseIfBlock parses the statements of a block IF up to ELSEIF, ELSE,
+// END IF or the end of the program, which becomes the current token.
+func (p *Parser) parseIfBlock() []ast.Statement {
+	stmts := []ast.Statement{}
+
+	for {
+		p.nextToken()
+
+		switch {
+		case p.curTokenIs(token.ELSEIF), p.curTokenIs(token.ELSE), p.curTokenIs(token.EOF):
+			return stmts
+		case p.curTokenIs(token.END) && p.peekTokenIs(token.IF):
+			return stmts
+		}
+
+		if s := p.parseStatement(); s != nil {
+			stmts = append(stmts, s)
+		}
+	}
+}
+
 func (p *Parser) parseOnStatement() *ast.OnStatement {
 	stmt := &ast.OnStatement{Token: p.curToken}
 
@@ -491,38 +812,63 @@ func (p *Parser) parseOnStatement() *ast.OnStatement {
 	return stmt
 }
 
-func (p *Parser) parseGotoIdentifiers() []*ast.Identifier {
-	idents := []*ast.Identifier{}
+func (p *Parser) parseOnErrorStatement() *ast.OnErrorStatement {
+	stmt := &ast.OnErrorStatement{Token: p.curToken}
+
+	p.nextToken() // ERROR
+
+	if !p.expectPeek(token.GOTO) {
+		return nil
+	}
 
 	i := p.parseGotoIdentifier()
 	if i == nil {
 		return nil
 	}
+	if i.Value != "0" {
+		stmt.Name = i
+	}
 
-	idents = append(idents, i)
-
-	for p.peekTokenIs(token.COMMA) {
+	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
+	}
 
+	return stmt
+}
+
+func (p *Parser) parseResumeStatement() *ast.ResumeStatement {
+	stmt := &ast.ResumeStatement{Token: p.curToken}
+
+	switch {
+	case p.peekTokenIs(token.NEXT):
+		p.nextToken()
+		stmt.Next = true
+	case p.peekTokenIs(token.NUM), p.peekTokenIs(token.ASTERISK):
 		i := p.parseGotoIdentifier()
 		if i == nil {
 			return nil
 		}
+		if i.Value != "0" {
+			stmt.Name = i
+		}
+	}
 
-		idents = append(idents, i)
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
 	}
 
-	return idents
+	return stmt
 }
 
-func (p *Parser) parseGotoStatement() *ast.GotoStatement {
-	stmt := &ast.GotoStatement{Token: p.curToken}
+func (p *Parser) parseErrorStatement() *ast.ErrorStatement {
+	stmt := &ast.ErrorStatement{Token: p.curToken}
 
-	i := p.parseGotoIdentifier()
-	if i == nil {
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
 		return nil
 	}
-	stmt.Name = i
 
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
@@ -531,45 +877,1021 @@ func (p *Parser) parseGotoStatement() *ast.GotoStatement {
 	return stmt
 }
 
-func (p *Parser) parseGosubStatement() *ast.GosubStatement {
-	stmt := &ast.GosubStatement{Token: p.curToken}
+func (p *Parser) parseGotoIdentifiers() []*ast.Identifier {
+	idents := []*ast.Identifier{}
 
 	i := p.parseGotoIdentifier()
 	if i == nil {
 		return nil
 	}
-	stmt.Name = i
 
-	if p.peekTokenIs(token.COLON) {
-		p.nextToken()
-	}
+	idents = append(idents, i)
 
-	return stmt
-}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		i := p.parseGotoIdentifier()
+		if i == nil {
+			return nil
+		}
+
+		idents = append(idents, i)
+	}
+
+	return idents
+}
+
+func (p *Parser) parseGotoStatement() *ast.GotoStatement {
+	stmt := &ast.GotoStatement{Token: p.curToken}
+
+	i := p.parseGotoIdentifier()
+	if i == nil {
+		return nil
+	}
+	stmt.Name = i
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseGosubStatement() *ast.GosubStatement {
+	stmt := &ast.GosubStatement{Token: p.curToken}
+
+	i := p.parseGotoIdentifier()
+	if i == nil {
+		return nil
+	}
+	stmt.Name = i
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
 
 func (p *Parser) parseGotoIdentifier() *ast.Identifier {
 	if p.peekTokenIs(token.NUM) {
 		p.nextToken()
 
-		// TODO: 整数限定
-		t := token.Token{Type: token.IDENT, Literal: p.curToken.Literal}
-		return &ast.Identifier{Token: t, Value: p.curToken.Literal}
-	} else if p.peekTokenIs(token.ASTERISK) {
-		p.nextToken()
+		// TODO: 整数限定
+		t := token.Token{Type: token.IDENT, Literal: p.curToken.Literal, Pos: p.curToken.Pos}
+		return &ast.Identifier{Token: t, Value: p.curToken.Literal}
+	} else if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	p.peekError(token.NUM)
+	return nil
+}
+
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	stmt := &ast.ReturnStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.EQ) {
+		return nil
+	}
+
+	p.nextToken()
+
+	b := p.parseExpression(LOWEST)
+	if b == nil {
+		return nil
+	}
+
+	stmt.Begin = b
+
+	if !p.expectPeek(token.TO) {
+		return nil
+	}
+
+	p.nextToken()
+
+	e := p.parseExpression(LOWEST)
+	if e == nil {
+		return nil
+	}
+
+	stmt.End = e
+
+	if p.peekTokenIs(token.STEP) {
+		p.nextToken()
+		p.nextToken()
+
+		s := p.parseExpression(LOWEST)
+		if s == nil {
+			return nil
+		}
+
+		stmt.Step = s
+	}
+
+	stmts, next, ok := p.parseLoopBody(stmt.Token, token.NEXT)
+	if !ok {
+		return nil
+	}
+
+	stmt.Statements = stmts
+
+	if next != nil {
+		stmt.IfNext = next
+		if next.Name != nil && types.Canonical(next.Name.Value) != types.Canonical(stmt.Name.Value) {
+			p.errorf(next.Name.Token, "NEXT %s does not match FOR %s", next.Name.Value, stmt.Name.Value)
+			return nil
+		}
+		return stmt
+	}
+
+	if !p.parseNext(stmt) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// loopStarts are the statements that start the loops ended by NEXT, WEND
+// and LOOP.
+var loopStarts = map[token.TokenType]string{
+	token.NEXT: token.FOR,
+	token.WEND: token.WHILE,
+	token.LOOP: token.DO,
+}
+
+// parseLoopBody parses the statements of the loop that starts at start,
+// up to the end token, which becomes the current token. The body may span
+// several lines. A FOR may also end at a NEXT in an IF, which is returned.
+func (p *Parser) parseLoopBody(start token.Token, end token.TokenType) ([]ast.Statement, *ast.NextStatement, bool) {
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	} else if !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) {
+		p.errorf(p.peekToken, "unexpected %s after %s", p.peekToken.Literal, start.Literal)
+		return nil, nil, false
+	}
+
+	p.nextToken()
+
+	l := &loop{start: start, ifs: len(p.ifs)}
+	p.loops = append(p.loops, l)
+	stmts := p.parseStatements(end, false)
+	p.loops = p.loops[:len(p.loops)-1]
+
+	if l.next != nil {
+		return stmts, l.next, true
+	}
+
+	// an empty body leaves the end token as the current one
+	if len(stmts) != 0 || !p.curTokenIs(end) {
+		if !p.peekTokenIs(end) {
+			p.errorf(start, "%s without %s", start.Literal, end)
+			return nil, nil, false
+		}
+		p.nextToken()
+	}
+
+	return stmts, nil, true
+}
+
+// nextEndsLoop reports whether the current NEXT is in a branch of an IF
+// that is a statement of a FOR. The NEXT ends the FOR.
+func (p *Parser) nextEndsLoop() bool {
+	if len(p.loops) == 0 {
+		return false
+	}
+	l := p.loops[len(p.loops)-1]
+	return l.start.Type == token.FOR && len(p.ifs) == l.ifs+1 && !p.ifs[len(p.ifs)-1].Block
+}
+
+// loopEnded reports whether the innermost loop has ended at a NEXT in an
+// IF, and the statements of the IF are parsed.
+func (p *Parser) loopEnded() bool {
+	if len(p.loops) == 0 {
+		return false
+	}
+	l := p.loops[len(p.loops)-1]
+	return l.next != nil && len(p.ifs) == l.ifs
+}
+
+// parseNextStatement parses a NEXT in a branch of an IF, which ends the
+// FOR around the IF. The statements after it run when the loop ends.
+func (p *Parser) parseNextStatement() *ast.NextStatement {
+	stmt := &ast.NextStatement{Token: p.curToken}
+
+	l := p.loops[len(p.loops)-1]
+	if l.next != nil {
+		p.errorf(stmt.Token, "%s has more than one NEXT in IF", l.start.Literal)
+		return nil
+	}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.errorf(p.peekToken, "NEXT in IF cannot end more than one FOR")
+		return nil
+	}
+
+	l.next = stmt
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil {
+		return nil
+	}
+
+	stmts, _, ok := p.parseLoopBody(stmt.Token, token.WEND)
+	if !ok {
+		return nil
+	}
+
+	stmt.Statements = stmts
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseDoStatement() *ast.DoStatement {
+	stmt := &ast.DoStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.WHILE) || p.peekTokenIs(token.UNTIL) {
+		p.nextToken()
+		if !p.parseDoCondition(stmt) {
+			return nil
+		}
+	}
+
+	p.doDepth++
+	stmts, _, ok := p.parseLoopBody(stmt.Token, token.LOOP)
+	p.doDepth--
+	if !ok {
+		return nil
+	}
+
+	stmt.Statements = stmts
+
+	if p.peekTokenIs(token.WHILE) || p.peekTokenIs(token.UNTIL) {
+		p.nextToken()
+		if stmt.Condition != nil {
+			p.errorf(p.curToken, "DO and LOOP cannot both have a condition")
+			return nil
+		}
+		stmt.Post = true
+		if !p.parseDoCondition(stmt) {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseDoCondition parses the condition after WHILE or UNTIL.
+func (p *Parser) parseDoCondition(stmt *ast.DoStatement) bool {
+	stmt.Until = p.curTokenIs(token.UNTIL)
+
+	p.nextToken()
+
+	stmt.Condition = p.parseExpression(LOWEST)
+	return stmt.Condition != nil
+}
+
+func (p *Parser) parseExitStatement() *ast.ExitStatement {
+	stmt := &ast.ExitStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.DO) {
+		return nil
+	}
+
+	stmt.Loop = p.curToken
+
+	if p.doDepth == 0 {
+		p.errorf(stmt.Token, "EXIT DO outside DO...LOOP")
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseNext parses the variables after NEXT. The first one must be the
+// variable of stmt. The rest of "NEXT J,I" belongs to the enclosing
+// loops, so the comma is overwritten to be the NEXT of the outer FOR.
+func (p *Parser) parseNext(stmt *ast.ForStatement) bool {
+	if !p.peekTokenIs(token.IDENT) {
+		return true
+	}
+	p.nextToken()
+
+	stmt.Next = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if types.Canonical(stmt.Next.Value) != types.Canonical(stmt.Name.Value) {
+		p.errorf(p.curToken, "NEXT %s does not match FOR %s", stmt.Next.Value, stmt.Name.Value)
+		return false
+	}
+
+	if p.peekTokenIs(token.COMMA) {
+		// overwrite the ',' token
+		p.peekToken.Type = token.NEXT
+		p.peekToken.Literal = token.NEXT
+	}
+
+	return true
+}
+
+func (p *Parser) parseDataStatement() *ast.DataStatement {
+	stmt := &ast.DataStatement{Token: p.curToken, Value: p.curToken.Literal}
+
+	stmt.Items = splitData(stmt.Value)
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// splitData splits the text of DATA into the items separated by commas.
+// A quoted item may have commas and colons.
+func splitData(s string) []ast.DataItem {
+	items := []ast.DataItem{}
+	if strings.TrimSpace(s) == "" {
+		return items
+	}
+
+	for {
+		s = strings.TrimLeft(s, " ")
+
+		var item ast.DataItem
+		if strings.HasPrefix(s, "\"") {
+			item.Quoted = true
+			s = s[1:]
+			if i := strings.IndexByte(s, '"'); i >= 0 {
+				item.Value = s[:i]
+				s = s[i+1:]
+			} else {
+				item.Value = s
+				s = ""
+			}
+			// ignore anything between the closing quote and the comma
+			if i := strings.IndexByte(s, ','); i >= 0 {
+				s = s[i:]
+			} else {
+				s = ""
+			}
+		} else {
+			i := strings.IndexByte(s, ',')
+			if i < 0 {
+				i = len(s)
+			}
+			item.Value = strings.TrimRight(s[:i], " ")
+			s = s[i:]
+		}
+
+		items = append(items, item)
+
+		if s == "" {
+			return items
+		}
+		s = s[1:] // ','
+	}
+}
+
+func (p *Parser) parseReadStatement() *ast.ReadStatement {
+	stmt := &ast.ReadStatement{Token: p.curToken}
+
+	names := p.parseVariables()
+	if names == nil {
+		return nil
+	}
+
+	stmt.Names = names
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseRestoreStatement() *ast.RestoreStatement {
+	stmt := &ast.RestoreStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.NUM) || p.peekTokenIs(token.ASTERISK) {
+		i := p.parseGotoIdentifier()
+		if i == nil {
+			return nil
+		}
+		stmt.Name = i
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseRandomizeStatement() *ast.RandomizeStatement {
+	stmt := &ast.RandomizeStatement{Token: p.curToken}
+
+	if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+
+		stmt.Value = p.parseExpression(LOWEST)
+		if stmt.Value == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEndStatement() *ast.EndStatement {
+	stmt := &ast.EndStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseStopStatement() *ast.StopStatement {
+	stmt := &ast.StopStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseClearStatement() *ast.ClearStatement {
+	stmt := &ast.ClearStatement{Token: p.curToken}
+
+	// the sizes of the memory and the stack, CLEAR ,&H8000,1024, mean
+	// nothing in C
+	for !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseSwapStatement() *ast.SwapStatement {
+	stmt := &ast.SwapStatement{Token: p.curToken}
+
+	stmt.Left = p.parseVariable()
+	if stmt.Left == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.COMMA) {
+		return nil
+	}
+
+	stmt.Right = p.parseVariable()
+	if stmt.Right == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEraseStatement() *ast.EraseStatement {
+	stmt := &ast.EraseStatement{Token: p.curToken}
+
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		stmt.Names = append(stmt.Names, name)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLetStatement() *ast.LetStatement {
+	t := token.Token{Type: token.LET, Literal: token.LET, Pos: p.curToken.Pos}
+	stmt := &ast.LetStatement{Token: t}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.EQ) {
+		return nil
+	}
+
+	p.nextToken()
+
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+
+	stmt.Value = value
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLetArrayStatement() *ast.LetStatement {
+	t := token.Token{Type: token.LET, Literal: token.LET, Pos: p.curToken.Pos}
+	stmt := &ast.LetStatement{Token: t}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	indices := p.parseIndices()
+	if indices == nil {
+		return nil
+	}
+
+	stmt.Name.Indices = indices
+
+	if !p.expectPeek(token.EQ) {
+		return nil
+	}
+
+	p.nextToken()
+
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+
+	stmt.Value = value
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseIndices() []ast.Expression {
+	indices := []ast.Expression{}
+
+	p.nextToken()
+
+	i := p.parseExpression(LOWEST)
+	if i == nil {
+		return nil
+	}
+
+	indices = append(indices, i)
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+
+		i := p.parseExpression(LOWEST)
+		if i == nil {
+			return nil
+		}
+
+		indices = append(indices, i)
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return indices
+}
+
+func (p *Parser) parseStatements(stopToken token.TokenType, stopByLine bool) []ast.Statement {
+	statements := []ast.Statement{}
+
+	if p.curTokenIs(stopToken) || (stopByLine && p.curTokenIs(token.LINENO)) || p.curTokenIs(token.EOF) {
+		// TODO: error message
+		return statements
+	}
+
+	stmt := p.parseStatement()
+	if stmt != nil {
+		statements = append(statements, stmt)
+	} else {
+		// ignore errors because the 'REM' statement returns nil
+	}
+
+	for !p.peekTokenIs(stopToken) && !(stopByLine && p.peekTokenIs(token.LINENO)) && !p.peekTokenIs(token.EOF) && !p.loopEnded() {
+		p.nextToken()
+
+		stmt := p.parseStatement()
+		if stmt != nil {
+			statements = append(statements, stmt)
+		} else {
+			// ignore errors because the 'REM' statement returns nil
+		}
+	}
+
+	return statements
+}
+
+func (p *Parser) parsePrintStatement() *ast.PrintStatement {
+	stmt := &ast.PrintStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.HASH) {
+		file := p.parseFileNumber(false)
+		if file == nil {
+			return nil
+		}
+		stmt.File = file
+
+		if !p.peekPrintEnd() && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.USING) {
+		p.nextToken()
+		p.nextToken()
+
+		format := p.parseExpression(LOWEST)
+		if format == nil {
+			return nil
+		}
+
+		stmt.Using = format
+
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	for !p.peekPrintEnd() {
+		p.nextToken()
+
+		item := ast.PrintItem{Token: p.curToken}
+
+		switch p.curToken.Type {
+		case token.SEMICOLON, token.COMMA:
+			item.Separator = p.curToken.Type
+			stmt.Items = append(stmt.Items, item)
+			continue
+		case token.TAB, token.SPC:
+			item.Func = p.curToken.Type
+
+			if !p.expectPeek(token.LPAREN) {
+				return nil
+			}
+			p.nextToken()
+
+			item.Value = p.parseExpression(LOWEST)
+			if item.Value == nil {
+				return nil
+			}
+
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		default:
+			item.Value = p.parseExpression(LOWEST)
+			if item.Value == nil {
+				return nil
+			}
+		}
+
+		if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			item.Separator = p.curToken.Type
+		} else if !p.peekPrintEnd() {
+			// PRINT A"X" is PRINT A;"X"
+			item.Separator = token.SEMICOLON
+		}
+
+		stmt.Items = append(stmt.Items, item)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// peekPrintEnd reports whether the next token ends the PRINT statement,
+// or a statement like it such as WRITE.
+func (p *Parser) peekPrintEnd() bool {
+	return p.peekTokenIs(token.COLON) || p.peekTokenIs(token.LINENO) ||
+		p.peekTokenIs(token.ELSE) || p.peekTokenIs(token.EOF)
+}
+
+func (p *Parser) parseInputStatement() *ast.InputStatement {
+	stmt := &ast.InputStatement{Token: p.curToken, Question: true}
+
+	if p.peekTokenIs(token.HASH) {
+		// INPUT #1, A has no prompt
+		file := p.parseFileNumber(false)
+		if file == nil {
+			return nil
+		}
+		stmt.File = file
+		stmt.Question = false
+
+		if !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	} else if p.peekTokenIs(token.STRING) {
+		p.nextToken()
+		stmt.Prompt = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+		// INPUT "X"; A prints "X? ", INPUT "X", A prints "X"
+		if p.peekTokenIs(token.COMMA) {
+			stmt.Question = false
+			p.nextToken()
+		} else if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	names := p.parseVariables()
+	if names == nil {
+		return nil
+	}
+
+	stmt.Names = names
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLineInputStatement() *ast.LineInputStatement {
+	stmt := &ast.LineInputStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.INPUT) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.HASH) {
+		file := p.parseFileNumber(false)
+		if file == nil {
+			return nil
+		}
+		stmt.File = file
+
+		if !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	} else if p.peekTokenIs(token.STRING) {
+		p.nextToken()
+		stmt.Prompt = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	name := p.parseVariable()
+	if name == nil {
+		return nil
+	}
+
+	stmt.Name = name
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseWriteStatement() *ast.WriteStatement {
+	stmt := &ast.WriteStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.HASH) {
+		file := p.parseFileNumber(false)
+		if file == nil {
+			return nil
+		}
+		stmt.File = file
+
+		if !p.peekPrintEnd() && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	for !p.peekPrintEnd() {
+		p.nextToken()
+
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		stmt.Values = append(stmt.Values, value)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseOpenStatement parses both forms of OPEN:
+//
+//	OPEN "DATA.TXT" FOR INPUT AS #1
+//	OPEN "I", #1, "DATA.TXT"
+//	OPEN "DATA.DAT" AS #2 LEN=32
+//	OPEN "R", #2, "DATA.DAT", 32
+func (p *Parser) parseOpenStatement() *ast.OpenStatement {
+	stmt := &ast.OpenStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	first := p.parseExpression(LOWEST)
+	if first == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COMMA) {
+		stmt.Mode = first
+		p.nextToken()
+
+		number := p.parseFileNumber(true)
+		if number == nil {
+			return nil
+		}
+		stmt.Number = number
+
+		if !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+
+		name := p.parseExpression(LOWEST)
+		if name == nil {
+			return nil
+		}
+		stmt.Name = name
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+
+			length := p.parseExpression(LOWEST)
+			if length == nil {
+				return nil
+			}
+			stmt.Length = length
+		}
+	} else {
+		stmt.Name = first
+
+		// a random file without FOR: OPEN "DATA.DAT" AS #1 LEN=32
+		mode := "R"
+		t := token.Token{Type: token.STRING, Literal: mode, Pos: p.peekToken.Pos}
+		if p.peekTokenIs(token.FOR) {
+			p.nextToken()
+			p.nextToken()
+
+			modes := map[token.TokenType]string{
+				token.INPUT: "I", token.OUTPUT: "O", token.APPEND: "A", token.RANDOM: "R",
+			}
+			m, ok := modes[p.curToken.Type]
+			if !ok {
+				p.errorf(p.curToken, "expected INPUT, OUTPUT, APPEND or RANDOM, got %s instead", p.curToken.Literal)
+				return nil
+			}
+			mode = m
+			t = token.Token{Type: token.STRING, Literal: mode, Pos: p.curToken.Pos}
+		}
+		stmt.Mode = &ast.StringLiteral{Token: t, Value: mode}
+
+		if !p.expectPeek(token.AS) {
+			return nil
+		}
 
-		if !p.expectPeek(token.IDENT) {
+		number := p.parseFileNumber(true)
+		if number == nil {
 			return nil
 		}
+		stmt.Number = number
 
-		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTokenIs(token.LEN) {
+			p.nextToken()
+			if !p.expectPeek(token.EQ) {
+				return nil
+			}
+			p.nextToken()
+
+			length := p.parseExpression(LOWEST)
+			if length == nil {
+				return nil
+			}
+			stmt.Length = length
+		}
 	}
 
-	p.peekError(token.NUM)
-	return nil
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+	}
+
+	return stmt
 }
 
-func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
-	stmt := &ast.ReturnStatement{Token: p.curToken}
+func (p *Parser) parseCloseStatement() *ast.CloseStatement {
+	stmt := &ast.CloseStatement{Token: p.curToken}
+
+	for !p.peekPrintEnd() {
+		number := p.parseFileNumber(true)
+		if number == nil {
+			return nil
+		}
+		stmt.Numbers = append(stmt.Numbers, number)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
 
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
@@ -578,75 +1900,82 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
-func (p *Parser) parseForStatement() *ast.ForStatement {
-	stmt := &ast.ForStatement{Token: p.curToken}
+func (p *Parser) parseKillStatement() *ast.KillStatement {
+	stmt := &ast.KillStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	p.nextToken()
+
+	name := p.parseExpression(LOWEST)
+	if name == nil {
 		return nil
 	}
+	stmt.Name = name
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
-	if !p.expectPeek(token.EQ) {
-		return nil
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
 	}
 
+	return stmt
+}
+
+func (p *Parser) parseNameStatement() *ast.NameStatement {
+	stmt := &ast.NameStatement{Token: p.curToken}
+
 	p.nextToken()
 
-	b := p.parseExpression(LOWEST)
-	if b == nil {
+	old := p.parseExpression(LOWEST)
+	if old == nil {
 		return nil
 	}
+	stmt.Old = old
 
-	stmt.Begin = b
-
-	if !p.expectPeek(token.TO) {
+	if !p.expectPeek(token.AS) {
 		return nil
 	}
-
 	p.nextToken()
 
-	e := p.parseExpression(LOWEST)
-	if e == nil {
+	name := p.parseExpression(LOWEST)
+	if name == nil {
 		return nil
 	}
+	stmt.New = name
 
-	stmt.End = e
-
-	if p.peekTokenIs(token.STEP) {
-		p.nextToken()
+	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
+	}
 
-		s := p.parseExpression(LOWEST)
-		if s == nil {
-			return nil
-		}
+	return stmt
+}
 
-		stmt.Step = s
-	} else {
-		t := token.Token{Type: token.NUM, Literal: "1"}
-		stmt.Step = &ast.IntegerLiteral{Token: t, Value: int64(1)}
-	}
+func (p *Parser) parseFieldStatement() *ast.FieldStatement {
+	stmt := &ast.FieldStatement{Token: p.curToken}
 
-	if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LINENO) {
-		// TODO: error message
+	file := p.parseFileNumber(true)
+	if file == nil {
 		return nil
-	} else if p.peekTokenIs(token.COLON) {
-		p.nextToken()
 	}
+	stmt.File = file
 
-	p.nextToken()
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
 
-	stmts := p.parseStatements(token.NEXT, false)
-	if stmts == nil {
-		// TODO: error message
-		return nil
-	}
+		width := p.parseExpression(LOWEST)
+		if width == nil {
+			return nil
+		}
 
-	stmt.Statements = stmts
+		if !p.expectPeek(token.AS) {
+			return nil
+		}
 
-	if !p.expectPeek(token.NEXT) {
-		return nil
+		name := p.parseVariable()
+		if name == nil {
+			return nil
+		}
+
+		stmt.Widths = append(stmt.Widths, width)
+		stmt.Names = append(stmt.Names, name)
 	}
 
 	if p.peekTokenIs(token.COLON) {
@@ -656,31 +1985,27 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	return stmt
 }
 
-func (p *Parser) parseDataStatement() *ast.DataStatement {
-	stmt := &ast.DataStatement{Token: p.curToken, Value: p.curToken.Literal}
-
-	return stmt
-}
-
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	t := token.Token{Type: token.LET, Literal: token.LET}
-	stmt := &ast.LetStatement{Token: t}
-
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+// parseRecordStatement parses GET and PUT of a random file.
+func (p *Parser) parseRecordStatement() *ast.RecordStatement {
+	stmt := &ast.RecordStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.EQ) {
+	file := p.parseFileNumber(true)
+	if file == nil {
 		return nil
 	}
+	stmt.File = file
 
-	p.nextToken()
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
 
-	value := p.parseExpression(LOWEST)
-	if value == nil {
-		return nil
+		record := p.parseExpression(LOWEST)
+		if record == nil {
+			return nil
+		}
+		stmt.Record = record
 	}
 
-	stmt.Value = value
-
 	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
 	}
@@ -688,34 +2013,25 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
-func (p *Parser) parseLetArrayStatement() *ast.LetStatement {
-	t := token.Token{Type: token.LET, Literal: token.LET}
-	stmt := &ast.LetStatement{Token: t}
-
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
+// parseSetStatement parses LSET and RSET.
+func (p *Parser) parseSetStatement() *ast.SetStatement {
+	stmt := &ast.SetStatement{Token: p.curToken}
 
-	indices := p.parseIndices()
-	if indices == nil {
+	name := p.parseVariable()
+	if name == nil {
 		return nil
 	}
-
-	stmt.Name.Indices = indices
+	stmt.Name = name
 
 	if !p.expectPeek(token.EQ) {
 		return nil
 	}
-
 	p.nextToken()
 
 	value := p.parseExpression(LOWEST)
 	if value == nil {
 		return nil
 	}
-
 	stmt.Value = value
 
 	if p.peekTokenIs(token.COLON) {
@@ -725,68 +2041,70 @@ func (p *Parser) parseLetArrayStatement() *ast.LetStatement {
 	return stmt
 }
 
-func (p *Parser) parseIndices() []ast.Expression {
-	indices := []ast.Expression{}
-
+// parseFileNumber parses the file number after the next token, #. The #
+// may be omitted if optional, as in CLOSE 1.
+func (p *Parser) parseFileNumber(optional bool) ast.Expression {
+	if p.peekTokenIs(token.HASH) {
+		p.nextToken()
+	} else if !optional {
+		p.peekError(token.HASH)
+		return nil
+	}
 	p.nextToken()
 
-	i := p.parseExpression(LOWEST)
-	if i == nil {
+	return p.parseExpression(LOWEST)
+}
+
+// parseVariables parses a comma separated list of variables.
+func (p *Parser) parseVariables() []*ast.Identifier {
+	names := []*ast.Identifier{}
+
+	name := p.parseVariable()
+	if name == nil {
 		return nil
 	}
 
-	indices = append(indices, i)
+	names = append(names, name)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
 
-		i := p.parseExpression(LOWEST)
-		if i == nil {
+		name := p.parseVariable()
+		if name == nil {
 			return nil
 		}
 
-		indices = append(indices, i)
-	}
-
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+		names = append(names, name)
 	}
 
-	return indices
+	return names
 }
 
-func (p *Parser) parseStatements(stopToken token.TokenType, stopByLine bool) []ast.Statement {
-	statements := []ast.Statement{}
-
-	if p.curTokenIs(stopToken) || (stopByLine && p.curTokenIs(token.LINENO)) || p.curTokenIs(token.EOF) {
-		// TODO: error message
-		return statements
+// parseVariable parses the next token as a scalar variable or an array
+// element.
+func (p *Parser) parseVariable() *ast.Identifier {
+	if !p.expectPeek(token.IDENT) {
+		return nil
 	}
 
-	stmt := p.parseStatement()
-	if stmt != nil {
-		statements = append(statements, stmt)
-	} else {
-		// ignore errors because the 'REM' statement returns nil
-	}
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	for !p.peekTokenIs(stopToken) && !(stopByLine && p.peekTokenIs(token.LINENO)) && !p.peekTokenIs(token.EOF) {
+	if p.peekTokenIs(token.LPAREN) {
 		p.nextToken()
 
-		stmt := p.parseStatement()
-		if stmt != nil {
-			statements = append(statements, stmt)
-		} else {
-			// ignore errors because the 'REM' statement returns nil
+		indices := p.parseIndices()
+		if indices == nil {
+			return nil
 		}
+
+		name.Indices = indices
 	}
 
-	return statements
+	return name
 }
 
 func (p *Parser) parseCallStatement() *ast.CallStatement {
-	t := token.Token{Type: token.CALL, Literal: token.CALL}
+	t := token.Token{Type: token.CALL, Literal: token.CALL, Pos: p.curToken.Pos}
 	stmt := &ast.CallStatement{Token: t}
 
 	f := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
@@ -827,7 +2145,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	}
 	leftExp := prefix()
 
-	for !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) && precedence < p.peekPrecedence() {
+	// an expression ends with its line: *L on the next line is a label
+	for !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.LINENO) && !p.peekTokenIs(token.EOF) &&
+		p.peekToken.Pos.Line == p.curToken.Pos.Line && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -860,6 +2180,10 @@ func (p *Parser) curPrecedence() int {
 func (p *Parser) parseIdentifier() ast.Expression {
 	var ident ast.Expression
 
+	if strings.HasPrefix(p.curToken.Literal, "FN") {
+		return p.parseFnCall()
+	}
+
 	if _, ok := p.dimVars[p.curToken.Literal]; ok {
 		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
@@ -877,39 +2201,146 @@ func (p *Parser) parseIdentifier() ast.Expression {
 		}
 
 		ident = name
-	} else if p.peekTokenIs(token.IDENT) || p.peekTokenIs(token.NUM) /*|| p.peekTokenIs(token.MINUS)*/ {
-		f := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	} else if f := builtin.Lookup(p.curToken.Literal); f != nil && f.Arity(0) && !p.peekTokenIs(token.LPAREN) {
+		// a function without arguments, such as RND
+		t := token.Token{Type: token.CALL, Literal: token.CALL, Pos: p.curToken.Pos}
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		ident = &ast.CallExpression{Token: t, Function: name}
+	} else {
+		ident = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	return ident
+}
+
+// parseFnCall parses the call of a user function: FNA(X, Y) or FNA
+// without arguments.
+func (p *Parser) parseFnCall() ast.Expression {
+	t := token.Token{Type: token.CALL, Literal: token.CALL, Pos: p.curToken.Pos}
+
+	name := p.parseFnName()
+	if name == nil {
+		return nil
+	}
 
+	exp := &ast.CallExpression{Token: t, Function: name}
+
+	if p.peekTokenIs(token.LPAREN) {
 		p.nextToken()
 
-		exp := p.parseCallExpression(f)
-		if exp == nil {
+		args := p.parseCallArguments()
+		if args == nil {
 			return nil
 		}
-
-		ident = exp
-	} else {
-		ident = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		exp.Arguments = args
 	}
 
-	return ident
+	return exp
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
+	if strings.HasPrefix(p.curToken.Literal, "&") {
+		return p.parseRadixLiteral()
+	}
+
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	value, err := strconv.ParseInt(strings.TrimSuffix(p.curToken.Literal, "%"), 10, 64)
+	if err != nil || (strings.HasSuffix(p.curToken.Literal, "%") && (value < -32768 || value > 32767)) {
+		p.errorf(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseNumberLiteral() ast.Expression {
+	s := p.curToken.Literal
+	switch {
+	case strings.HasPrefix(s, "&"), strings.HasSuffix(s, "%"):
+		return p.parseIntegerLiteral()
+	case strings.HasSuffix(s, "!"), strings.HasSuffix(s, "#"):
+		return p.parseFloatLiteral()
+	case strings.ContainsAny(s, ".EeDd"):
+		return p.parseFloatLiteral()
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+		return p.parseFloatLiteral() // too large for an integer
+	}
+	return p.parseIntegerLiteral()
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken, Type: types.Single}
+
+	// 1D2 is a double precision number
+	s := p.curToken.Literal
+	if i := strings.IndexAny(s, "Dd"); i >= 0 {
+		s = s[:i] + "E" + s[i+1:]
+		lit.Type = types.Double
+	}
+
+	// a suffix decides the type: 1.23456789! is a single
+	suffix := types.Invalid
+	if types.BaseName(s) != s {
+		suffix = types.FromName(s)
+		s = types.BaseName(s)
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
 	lit.Value = value
+	switch {
+	case suffix != types.Invalid:
+		lit.Type = suffix
+	case significantDigits(s) > 7:
+		lit.Type = types.Double
+	}
 
 	return lit
 }
 
+// parseRadixLiteral parses &H7F, &O17 or &B1010 as a 16-bit integer, so
+// that &HFFFF is -1.
+func (p *Parser) parseRadixLiteral() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken}
+
+	bases := map[byte]int{'H': 16, 'O': 8, 'B': 2}
+	s := p.curToken.Literal
+	value, err := strconv.ParseUint(s[2:], bases[s[1]], 64)
+	if err != nil || value > 0xFFFF {
+		p.errorf(p.curToken, "could not parse %q as integer", s)
+		return nil
+	}
+
+	lit.Value = int64(int16(value))
+
+	return lit
+}
+
+// significantDigits counts the digits of the mantissa of a number
+// without its leading zeros.
+func significantDigits(s string) int {
+	if i := strings.IndexAny(s, "Ee"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimLeft(s, "0.")
+
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if '0' <= s[i] && s[i] <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -920,13 +2351,52 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 		Operator: p.curToken.Literal,
 	}
 
+	precedence := PREFIX
+	if p.curTokenIs(token.NOT) {
+		precedence = LOGICNOT // NOT A=B is NOT (A=B)
+	}
+
 	p.nextToken()
 
-	expression.Right = p.parseExpression(PREFIX)
+	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+// parseKeywordCall parses a function whose name is a keyword, such as
+// LEN(A$), as a call. The parentheses may be omitted for one argument.
+func (p *Parser) parseKeywordCall() ast.Expression {
+	f := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	t := token.Token{Type: token.CALL, Literal: token.CALL, Pos: p.curToken.Pos}
+	exp := &ast.CallExpression{Token: t, Function: f}
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+
+		args := p.parseCallArguments()
+		if args == nil {
+			return nil
+		}
+		exp.Arguments = args
+
+		if !p.checkArguments(exp) {
+			return nil
+		}
+
+		return exp
+	}
+
+	p.nextToken()
+
+	arg := p.parseExpression(PREFIX)
+	if arg == nil {
+		return nil
+	}
+	exp.Arguments = []ast.Expression{arg}
+
+	return exp
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -956,12 +2426,11 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	f, ok := function.(*ast.Identifier)
 	if !ok {
-		msg := fmt.Sprintf("could not parse %q as identifier", function.TokenLiteral())
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "could not parse %q as identifier", function.TokenLiteral())
 		return nil
 	}
 
-	t := token.Token{Type: token.CALL, Literal: token.CALL}
+	t := token.Token{Type: token.CALL, Literal: token.CALL, Pos: f.Token.Pos}
 	exp := &ast.CallExpression{Token: t, Function: f}
 
 	args := p.parseCallArguments()
@@ -970,9 +2439,26 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	}
 	exp.Arguments = args
 
+	if !p.checkArguments(exp) {
+		return nil
+	}
+
 	return exp
 }
 
+// checkArguments reports a call of a builtin function with a wrong number
+// of arguments. Their types are checked by the semantic analysis, which
+// knows the types of the variables.
+func (p *Parser) checkArguments(exp *ast.CallExpression) bool {
+	f := builtin.Lookup(exp.Function.Value)
+	if f == nil || f.Arity(len(exp.Arguments)) {
+		return true
+	}
+
+	p.errorf(exp.Function.Token, "wrong number of arguments to %s", f.Name)
+	return false
+}
+
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
 
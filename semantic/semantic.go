@@ -0,0 +1,471 @@
+package semantic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/builtin"
+	"github.com/ysh86/b2c/token"
+	"github.com/ysh86/b2c/types"
+)
+
+// Error is reported when the program is syntactically valid but meaningless.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Symbol is a variable of the program.
+type Symbol struct {
+	Name string
+	Type types.Type
+	Dims []int64        // upper bounds of an array, nil for a scalar
+	Pos  token.Position // where it first appears
+}
+
+func (s *Symbol) IsArray() bool { return s.Dims != nil }
+
+// Table holds the variables of a program keyed by their canonical names
+// (see types.Canonical). Scalars and arrays live in separate namespaces,
+// so A and A() are different variables. The user functions are keyed the
+// same way.
+type Table struct {
+	Scalars map[string]*Symbol
+	Arrays  map[string]*Symbol
+	Funcs   map[string]*ast.DefFnStatement
+}
+
+func NewTable() *Table {
+	return &Table{
+		Scalars: make(map[string]*Symbol),
+		Arrays:  make(map[string]*Symbol),
+		Funcs:   make(map[string]*ast.DefFnStatement),
+	}
+}
+
+// ScalarList returns the scalars sorted by name.
+func (t *Table) ScalarList() []*Symbol { return sortedSymbols(t.Scalars) }
+
+// ArrayList returns the arrays sorted by name.
+func (t *Table) ArrayList() []*Symbol { return sortedSymbols(t.Arrays) }
+
+// FuncList returns the user functions sorted by name.
+func (t *Table) FuncList() []*ast.DefFnStatement {
+	list := make([]*ast.DefFnStatement, 0, len(t.Funcs))
+	for _, f := range t.Funcs {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return types.Canonical(list[i].Name.Value) < types.Canonical(list[j].Name.Value)
+	})
+	return list
+}
+
+func sortedSymbols(m map[string]*Symbol) []*Symbol {
+	list := make([]*Symbol, 0, len(m))
+	for _, s := range m {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return types.Canonical(list[i].Name) < types.Canonical(list[j].Name)
+	})
+	return list
+}
+
+type analyzer struct {
+	table  *Table
+	params map[string]bool // the parameters of the DEF FN being checked
+	err    *Error          // the first error
+}
+
+// Analyze collects the variables of program and checks their types.
+func Analyze(program *ast.Program) (*Table, error) {
+	a := &analyzer{table: NewTable()}
+
+	// a function may be called before its DEF FN
+	ast.Inspect(program, a.define)
+	ast.Inspect(program, a.visit)
+
+	if a.err != nil {
+		return a.table, a.err
+	}
+	return a.table, nil
+}
+
+func (a *analyzer) errorf(node ast.Node, format string, args ...interface{}) {
+	if a.err != nil {
+		return
+	}
+	a.err = &Error{Pos: node.Pos(), Msg: fmt.Sprintf(format, args...)}
+}
+
+// define records the user functions.
+func (a *analyzer) define(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.DefFnStatement:
+		key := types.Canonical(n.Name.Value)
+		if _, ok := a.table.Funcs[key]; ok {
+			a.errorf(n.Name, "duplicate definition: %s", n.Name.Value)
+			return false
+		}
+		a.table.Funcs[key] = n
+		return false
+	case ast.Expression:
+		return false
+	}
+	return true
+}
+
+func (a *analyzer) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.LineNoStatement:
+		return false
+	case *ast.LabelStatement, *ast.GotoStatement, *ast.GosubStatement, *ast.RestoreStatement,
+		*ast.OnErrorStatement, *ast.ResumeStatement:
+		return false
+	case *ast.CallStatement:
+		// a command b2c does not know, such as CLS
+		a.errorf(n, "unsupported statement: %s", n.Expression.Function.Value)
+		return false
+	case *ast.OnStatement:
+		a.numeric(n.Value)
+		ast.Inspect(n.Value, a.visit)
+		return false
+	case *ast.DimStatement:
+		for i, name := range n.Names {
+			a.dim(name, n.Values[i])
+		}
+		return false
+	case *ast.DefFnStatement:
+		a.defFn(n)
+		return false
+	case *ast.ForStatement:
+		if !types.FromName(n.Name.Value).IsNumeric() {
+			a.errorf(n.Name, "type mismatch: FOR variable %s must be numeric", n.Name.Value)
+		}
+		a.numeric(n.Begin)
+		a.numeric(n.End)
+		a.numeric(n.Step)
+	case *ast.WhileStatement:
+		a.numeric(n.Condition)
+	case *ast.DoStatement:
+		a.numeric(n.Condition)
+	case *ast.RandomizeStatement:
+		a.numeric(n.Value)
+	case *ast.ErrorStatement:
+		a.numeric(n.Value)
+	case *ast.SwapStatement:
+		if l, r := TypeOf(n.Left), TypeOf(n.Right); l != r {
+			a.errorf(n.Right, "type mismatch: cannot swap %s %s and %s %s", l, n.Left.Value, r, n.Right.Value)
+		}
+	case *ast.EraseStatement:
+		for _, name := range n.Names {
+			if _, ok := a.table.Arrays[types.Canonical(name.Value)]; !ok {
+				a.errorf(name, "array %s is not declared by DIM", name.Value)
+			}
+		}
+		return false
+	case *ast.LetStatement:
+		a.assignable(n.Name, n.Value)
+	case *ast.OpenStatement:
+		a.str(n.Mode)
+		a.numeric(n.Number)
+		a.str(n.Name)
+		a.numeric(n.Length)
+	case *ast.CloseStatement:
+		for _, number := range n.Numbers {
+			a.numeric(number)
+		}
+	case *ast.WriteStatement:
+		a.numeric(n.File)
+	case *ast.FieldStatement:
+		a.numeric(n.File)
+		for i, w := range n.Widths {
+			a.numeric(w)
+			if TypeOf(n.Names[i]) != types.String {
+				a.errorf(n.Names[i], "type mismatch: FIELD variable %s must be a string", n.Names[i].Value)
+			}
+		}
+	case *ast.RecordStatement:
+		a.numeric(n.File)
+		a.numeric(n.Record)
+	case *ast.SetStatement:
+		if TypeOf(n.Name) != types.String {
+			a.errorf(n.Name, "type mismatch: %s variable %s must be a string", n.Token.Literal, n.Name.Value)
+		}
+		a.str(n.Value)
+	case *ast.KillStatement:
+		a.str(n.Name)
+	case *ast.NameStatement:
+		a.str(n.Old)
+		a.str(n.New)
+	case *ast.InputStatement:
+		a.numeric(n.File)
+	case *ast.LineInputStatement:
+		a.numeric(n.File)
+		if TypeOf(n.Name) != types.String {
+			a.errorf(n.Name, "type mismatch: LINE INPUT variable %s must be a string", n.Name.Value)
+		}
+	case *ast.PrintStatement:
+		a.numeric(n.File)
+		if n.Using != nil {
+			a.str(n.Using)
+		}
+		for _, item := range n.Items {
+			if item.Func != "" {
+				a.numeric(item.Value)
+			}
+		}
+	case *ast.CallExpression:
+		for _, arg := range n.Arguments {
+			ast.Inspect(arg, a.visit)
+		}
+		if f := builtin.Lookup(n.Function.Value); f != nil {
+			a.call(f, n)
+		} else if n.Function.IsFn() {
+			a.fnCall(n)
+		} else {
+			a.errorf(n, "undefined function %s", n.Function.Value)
+		}
+		return false
+	case *ast.PrefixExpression:
+		a.numeric(n.Right)
+	case *ast.InfixExpression:
+		a.operands(n)
+	case *ast.Identifier:
+		a.use(n)
+	}
+	return true
+}
+
+func (a *analyzer) dim(name *ast.Identifier, values []*ast.IntegerLiteral) {
+	dims := []int64{}
+	for _, v := range values {
+		dims = append(dims, v.Value)
+	}
+
+	key := types.Canonical(name.Value)
+	s, ok := a.table.Arrays[key]
+	if !ok {
+		a.table.Arrays[key] = &Symbol{
+			Name: name.Value,
+			Type: types.FromName(name.Value),
+			Dims: dims,
+			Pos:  name.Pos(),
+		}
+		return
+	}
+
+	// DIM again: keep the largest bounds
+	if len(s.Dims) != len(dims) {
+		a.errorf(name, "duplicate definition: %s has %d dimensions", name.Value, len(s.Dims))
+		return
+	}
+	for i, d := range dims {
+		if d > s.Dims[i] {
+			s.Dims[i] = d
+		}
+	}
+}
+
+func (a *analyzer) use(i *ast.Identifier) {
+	key := types.Canonical(i.Value)
+	if len(i.Indices) == 0 {
+		if a.params[key] {
+			return // not a global variable
+		}
+		if _, ok := a.table.Scalars[key]; !ok {
+			a.table.Scalars[key] = &Symbol{
+				Name: i.Value,
+				Type: types.FromName(i.Value),
+				Pos:  i.Pos(),
+			}
+		}
+		return
+	}
+
+	s, ok := a.table.Arrays[key]
+	if !ok {
+		a.errorf(i, "array %s is not declared by DIM", i.Value)
+		return
+	}
+	if len(s.Dims) != len(i.Indices) {
+		a.errorf(i, "wrong number of subscripts for %s: got %d, want %d",
+			i.Value, len(i.Indices), len(s.Dims))
+	}
+	for _, e := range i.Indices {
+		a.numeric(e)
+	}
+}
+
+func (a *analyzer) assignable(target *ast.Identifier, value ast.Expression) {
+	tt := TypeOf(target)
+	vt := TypeOf(value)
+	if tt == types.Invalid || vt == types.Invalid {
+		return
+	}
+	if (tt == types.String) != (vt == types.String) {
+		a.errorf(value, "type mismatch: cannot assign %s to %s", vt, target.Value)
+	}
+}
+
+func (a *analyzer) numeric(e ast.Expression) {
+	if e == nil {
+		return
+	}
+	if TypeOf(e) == types.String {
+		a.errorf(e, "type mismatch: %s is not numeric", e.String())
+	}
+}
+
+// call checks the arguments of the builtin function f.
+func (a *analyzer) call(f *builtin.Func, e *ast.CallExpression) {
+	if !f.Arity(len(e.Arguments)) {
+		a.errorf(e, "wrong number of arguments to %s", f.Name)
+		return
+	}
+
+	argTypes := []types.Type{}
+	for _, arg := range e.Arguments {
+		argTypes = append(argTypes, TypeOf(arg))
+	}
+	if f.Match(argTypes) != nil {
+		return
+	}
+
+	// report the first argument that matches no signature
+	for _, sig := range f.Sigs {
+		if len(sig) != len(e.Arguments) {
+			continue
+		}
+		for i, k := range sig {
+			if k == builtin.String {
+				a.str(e.Arguments[i])
+			} else {
+				a.numeric(e.Arguments[i])
+			}
+		}
+	}
+}
+
+// defFn checks the body of a user function. Its parameters are local to
+// the body, where they hide the variables of the same names.
+func (a *analyzer) defFn(s *ast.DefFnStatement) {
+	a.params = make(map[string]bool)
+	for _, param := range s.Parameters {
+		key := types.Canonical(param.Value)
+		if a.params[key] {
+			a.errorf(param, "duplicate parameter %s in %s", param.Value, s.Name.Value)
+		}
+		a.params[key] = true
+	}
+
+	ast.Inspect(s.Value, a.visit)
+	a.params = nil
+
+	ft, vt := TypeOf(s.Name), TypeOf(s.Value)
+	if ft != types.Invalid && vt != types.Invalid && (ft == types.String) != (vt == types.String) {
+		a.errorf(s.Value, "type mismatch: %s cannot return %s", s.Name.Value, vt)
+	}
+}
+
+// fnCall checks the arguments of a call of a user function.
+func (a *analyzer) fnCall(e *ast.CallExpression) {
+	f, ok := a.table.Funcs[types.Canonical(e.Function.Value)]
+	if !ok {
+		a.errorf(e, "undefined user function %s", e.Function.Value)
+		return
+	}
+	if len(e.Arguments) != len(f.Parameters) {
+		a.errorf(e, "wrong number of arguments to %s", e.Function.Value)
+		return
+	}
+
+	for i, param := range f.Parameters {
+		if TypeOf(param) == types.String {
+			a.str(e.Arguments[i])
+		} else {
+			a.numeric(e.Arguments[i])
+		}
+	}
+}
+
+// operands checks that both sides of e are strings or numbers.
+func (a *analyzer) operands(e *ast.InfixExpression) {
+	l, r := TypeOf(e.Left), TypeOf(e.Right)
+	if l == types.Invalid || r == types.Invalid {
+		return
+	}
+	if (l == types.String) != (r == types.String) {
+		a.errorf(e, "type mismatch: %s %s %s", l, e.Operator, r)
+		return
+	}
+	if l == types.String {
+		switch e.Token.Type {
+		case token.MINUS, token.ASTERISK, token.SLASH, token.BACKSLASH, token.MOD, token.CARET,
+			token.AND, token.OR, token.XOR, token.EQV, token.IMP:
+			a.errorf(e, "type mismatch: %s is not numeric", e.Left.String())
+		}
+	}
+}
+
+func (a *analyzer) str(e ast.Expression) {
+	if t := TypeOf(e); t != types.String && t != types.Invalid {
+		a.errorf(e, "type mismatch: %s is not a string", e.String())
+	}
+}
+
+// TypeOf returns the type of the expression e.
+func TypeOf(e ast.Expression) types.Type {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		switch {
+		case -32768 <= e.Value && e.Value <= 32767:
+			return types.Integer
+		case len(strconv.FormatInt(e.Value, 10)) <= 7:
+			return types.Single
+		}
+		return types.Double
+	case *ast.FloatLiteral:
+		return e.Type
+	case *ast.StringLiteral:
+		return types.String
+	case *ast.Identifier:
+		return types.FromName(e.Value)
+	case *ast.PrefixExpression:
+		if e.Token.Type == token.NOT {
+			return types.Integer
+		}
+		return TypeOf(e.Right)
+	case *ast.InfixExpression:
+		l, r := TypeOf(e.Left), TypeOf(e.Right)
+		switch e.Token.Type {
+		case token.PLUS:
+			if l == types.String && r == types.String {
+				return types.String
+			}
+			return types.Promote(l, r)
+		case token.MINUS, token.ASTERISK:
+			return types.Promote(l, r)
+		case token.SLASH, token.CARET:
+			return types.Promote(types.Promote(l, r), types.Single)
+		}
+		return types.Integer // \, MOD, relational and logical operators
+	case *ast.CallExpression:
+		if f := builtin.Lookup(e.Function.Value); f != nil {
+			argTypes := []types.Type{}
+			for _, arg := range e.Arguments {
+				argTypes = append(argTypes, TypeOf(arg))
+			}
+			return f.Type(argTypes)
+		}
+		return types.FromName(e.Function.Value)
+	}
+	return types.Invalid
+}
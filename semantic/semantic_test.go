@@ -0,0 +1,224 @@
+package semantic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ysh86/b2c/ast"
+	"github.com/ysh86/b2c/lexer"
+	"github.com/ysh86/b2c/parser"
+	"github.com/ysh86/b2c/types"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(strings.NewReader(input)))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("parser error: %v", err)
+	}
+
+	return program
+}
+
+func TestSymbols(t *testing.T) {
+	input := `10 DIM A(5),B$(2,3),C%(1)
+20 A=1:A$="X":A(1)=A:B$(0,1)=A$
+30 FOR I%=1 TO 10:D#=D#+C%(0):NEXT
+40 IF E!>0 THEN F=LEN(A$)
+`
+	table, err := Analyze(parse(t, input))
+	if err != nil {
+		t.Fatalf("Analyze error: %v", err)
+	}
+
+	scalars := []struct {
+		name string
+		typ  types.Type
+	}{
+		{"A", types.Single},
+		{"A$", types.String},
+		{"D#", types.Double},
+		{"E!", types.Single},
+		{"F", types.Single},
+		{"I%", types.Integer},
+	}
+	list := table.ScalarList()
+	if len(list) != len(scalars) {
+		t.Fatalf("wrong number of scalars. expected=%d, got=%d", len(scalars), len(list))
+	}
+	for i, s := range scalars {
+		if list[i].Name != s.name || list[i].Type != s.typ || list[i].IsArray() {
+			t.Errorf("scalars[%d] wrong. expected=%s %s, got=%s %s", i, s.name, s.typ, list[i].Name, list[i].Type)
+		}
+	}
+
+	arrays := []struct {
+		name string
+		typ  types.Type
+		dims []int64
+	}{
+		{"A", types.Single, []int64{5}},
+		{"B$", types.String, []int64{2, 3}},
+		{"C%", types.Integer, []int64{1}},
+	}
+	list = table.ArrayList()
+	if len(list) != len(arrays) {
+		t.Fatalf("wrong number of arrays. expected=%d, got=%d", len(arrays), len(list))
+	}
+	for i, a := range arrays {
+		if list[i].Name != a.name || list[i].Type != a.typ || len(list[i].Dims) != len(a.dims) {
+			t.Errorf("arrays[%d] wrong. expected=%s %s %v, got=%s %s %v", i,
+				a.name, a.typ, a.dims, list[i].Name, list[i].Type, list[i].Dims)
+			continue
+		}
+		for j, d := range a.dims {
+			if list[i].Dims[j] != d {
+				t.Errorf("arrays[%d] dims wrong. expected=%v, got=%v", i, a.dims, list[i].Dims)
+			}
+		}
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	input := `10 DEF FNA(X,Y$)=X+LEN(Y$)+Z
+20 DEF FN B$=Y$
+30 PRINT FNA(1,"A");FNB$
+`
+	table, err := Analyze(parse(t, input))
+	if err != nil {
+		t.Fatalf("Analyze error: %v", err)
+	}
+
+	// the parameters are not global variables
+	scalars := []string{"Y$", "Z"}
+	list := table.ScalarList()
+	if len(list) != len(scalars) {
+		t.Fatalf("wrong number of scalars. expected=%d, got=%d", len(scalars), len(list))
+	}
+	for i, name := range scalars {
+		if list[i].Name != name {
+			t.Errorf("scalars[%d] wrong. expected=%s, got=%s", i, name, list[i].Name)
+		}
+	}
+
+	funcs := []string{"FNA", "FNB$"}
+	fl := table.FuncList()
+	if len(fl) != len(funcs) {
+		t.Fatalf("wrong number of functions. expected=%d, got=%d", len(funcs), len(fl))
+	}
+	for i, name := range funcs {
+		if fl[i].Name.Value != name {
+			t.Errorf("funcs[%d] wrong. expected=%s, got=%s", i, name, fl[i].Name.Value)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`10 A="X"`, `1:6: type mismatch: cannot assign string to A`},
+		{`10 A$=1+2`, `1:8: type mismatch: cannot assign integer to A$`},
+		{`10 FOR I$=1 TO 2:A=I$:NEXT`, `1:8: type mismatch: FOR variable I$ must be numeric`},
+		{`10 DIM A(2):A(1,2)=0`, `1:13: wrong number of subscripts for A: got 2, want 1`},
+		{`10 DIM A(2):DIM A(3,4)`, `1:17: duplicate definition: A has 1 dimensions`},
+		{`10 ON "X" GOTO 10`, `1:7: type mismatch: "X" is not numeric`},
+		{`10 PRINT TAB(A$)`, `1:14: type mismatch: A$ is not numeric`},
+		{`10 PRINT USING 1;A`, `1:16: type mismatch: 1 is not a string`},
+		{`10 LINE INPUT A`, `1:15: type mismatch: LINE INPUT variable A must be a string`},
+		{`10 A=A$+1`, `1:8: type mismatch: string + integer`},
+		{`10 A$=MID$(1,2)`, `1:12: type mismatch: 1 is not a string`},
+		{`10 A=A$ MOD B$`, `1:9: type mismatch: A$ is not numeric`},
+		{`10 A=NOT A$`, `1:10: type mismatch: A$ is not numeric`},
+		{`10 WHILE A$:WEND`, `1:10: type mismatch: A$ is not numeric`},
+		{`10 DO:LOOP UNTIL A$`, `1:18: type mismatch: A$ is not numeric`},
+		{`10 A=SQR(A$)`, `1:10: type mismatch: A$ is not numeric`},
+		{`10 RANDOMIZE A$`, `1:14: type mismatch: A$ is not numeric`},
+		{`10 ERROR A$`, `1:10: type mismatch: A$ is not numeric`},
+		{`10 SWAP A%,B`, `1:12: type mismatch: cannot swap integer A% and single B`},
+		{`10 ERASE A`, `1:10: array A is not declared by DIM`},
+		{`10 DEF FNA(X)=X:DEF FNA!(Y)=Y`, `1:21: duplicate definition: FNA!`},
+		{`10 DEF FNA(X,X)=X`, `1:14: duplicate parameter X in FNA`},
+		{`10 OPEN 1 FOR INPUT AS #1`, `1:9: type mismatch: 1 is not a string`},
+		{`10 PRINT #A$, 1`, `1:11: type mismatch: A$ is not numeric`},
+		{`10 NAME "A" AS B`, `1:16: type mismatch: B is not a string`},
+		{`10 A=EOF(A$)`, `1:10: type mismatch: A$ is not numeric`},
+		{`10 FIELD #1, 2 AS A`, `1:19: type mismatch: FIELD variable A must be a string`},
+		{`10 RSET A=""`, `1:9: type mismatch: RSET variable A must be a string`},
+		{`10 A=CVI(1)`, `1:10: type mismatch: 1 is not a string`},
+		{`10 DEF FNA$(X)=X+1`, `1:17: type mismatch: FNA$ cannot return single`},
+		{`10 PRINT FNA(1)`, `1:10: undefined user function FNA`},
+		{`10 PRINT FOO(1)`, `1:10: undefined function FOO`},
+		{`10 X=1+BAR(X,2)`, `1:8: undefined function BAR`},
+		{`10 CLS`, `1:4: unsupported statement: CLS`},
+		{`10 DEF FNA(X)=FNB(X)+1`, `1:15: undefined user function FNB`},
+		{`10 DEF FNA(X)=X:PRINT FNA(1,2)`, `1:23: wrong number of arguments to FNA`},
+		{`10 DEF FNA(X$)=1:PRINT FNA(2)`, `1:28: type mismatch: 2 is not a string`},
+	}
+
+	for _, tt := range tests {
+		_, err := Analyze(parse(t, tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("input=%q: expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected types.Type
+	}{
+		{"A=1", types.Integer},
+		{"A=32768", types.Single},
+		{"A=12345678", types.Double},
+		{"A=1.5", types.Single},
+		{"A=1.5E3", types.Single},
+		{"A=1D3", types.Double},
+		{"A=1.23456789", types.Double},
+		{"A=10%", types.Integer},
+		{"A=10!", types.Single},
+		{"A=1.23456789!", types.Single},
+		{"A=2#", types.Double},
+		{"A=&HFFFF", types.Integer},
+		{"A=A%+A#", types.Double},
+		{"A=1/2", types.Single},
+		{"A=A$+B$", types.String},
+		{"A=A$<>B$", types.Integer},
+		{"A=A#\\2", types.Integer},
+		{"A=A# MOD 2", types.Integer},
+		{"A=A%^2", types.Single},
+		{"A=A#^2", types.Double},
+		{"A=NOT A#", types.Integer},
+		{"A=LEN(A$)", types.Integer},
+		{"A=CHR$(65)", types.String},
+		{"A=MID$(A$,2)", types.String},
+		{"A=INSTR(A$,B$)", types.Integer},
+		{"A=VAL(A$)", types.Single},
+		{"A=ABS(I%)", types.Integer},
+		{"A=INT(A#)", types.Double},
+		{"A=SQR(A#)", types.Single},
+		{"A=RND", types.Single},
+		{"A=CINT(A)", types.Integer},
+		{"A=CDBL(A)", types.Double},
+		{"A=ERR", types.Integer},
+		{"A=ERL", types.Single},
+		{"A=FNA(1)", types.Single},
+		{"A=FNB$(A$)", types.String},
+		{"A=FN C%", types.Integer},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		let, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("input=%q: not *ast.LetStatement. got=%T", tt.input, program.Statements[0])
+		}
+		if got := TypeOf(let.Value); got != tt.expected {
+			t.Errorf("input=%q: expected=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -24,6 +24,8 @@ OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 SOFTWARE.
 */
 
+import "strconv"
+
 type TokenType string
 
 const (
@@ -37,20 +39,25 @@ const (
 	LINENO = "LINENO" // line number
 
 	// Operators
-	PLUS     = "+"
-	MINUS    = "-"
-	ASTERISK = "*"
-	SLASH    = "/"
+	PLUS      = "+"
+	MINUS     = "-"
+	ASTERISK  = "*"
+	SLASH     = "/"
+	BACKSLASH = "\\" // integer division
+	CARET     = "^"
 
 	LT     = "<"
 	GT     = ">"
 	EQ     = "="
-	NOT_EQ = "<>"
+	NOT_EQ = "<>" // or ><
+	LT_EQ  = "<=" // or =<
+	GT_EQ  = ">=" // or =>
 
 	// Delimiters
 	SEMICOLON = ";"
 	COMMA     = ","
 	COLON     = ":"
+	HASH      = "#" // file number
 
 	LPAREN = "("
 	RPAREN = ")"
@@ -63,49 +70,154 @@ const (
 	ASC   = "ASC"
 	CHR_D = "CHR$"
 	// Keywords
-	DIM    = "DIM"
-	IF     = "IF"
-	THEN   = "THEN"
-	ELSE   = "ELSE"
-	ON     = "ON"
-	GOTO   = "GOTO"
-	GOSUB  = "GOSUB"
-	RETURN = "RETURN"
-	FOR    = "FOR"
-	TO     = "TO"
-	STEP   = "STEP"
-	NEXT   = "NEXT"
-	DATA   = "DATA"
-	REM    = "REM"
-	AND    = "AND"
-	OR     = "OR"
+	DIM       = "DIM"
+	DEF       = "DEF"
+	IF        = "IF"
+	THEN      = "THEN"
+	ELSE      = "ELSE"
+	ELSEIF    = "ELSEIF"
+	END       = "END"
+	STOP      = "STOP"
+	CLEAR     = "CLEAR"
+	SWAP      = "SWAP"
+	ERASE     = "ERASE"
+	ON        = "ON"
+	GOTO      = "GOTO"
+	GOSUB     = "GOSUB"
+	RETURN    = "RETURN"
+	ERROR     = "ERROR"
+	RESUME    = "RESUME"
+	FOR       = "FOR"
+	TO        = "TO"
+	STEP      = "STEP"
+	NEXT      = "NEXT"
+	WHILE     = "WHILE"
+	WEND      = "WEND"
+	DO        = "DO"
+	LOOP      = "LOOP"
+	UNTIL     = "UNTIL"
+	EXIT      = "EXIT"
+	DATA      = "DATA"
+	REM       = "REM"
+	AND       = "AND"
+	OR        = "OR"
+	NOT       = "NOT"
+	XOR       = "XOR"
+	EQV       = "EQV"
+	IMP       = "IMP"
+	MOD       = "MOD"
+	PRINT     = "PRINT"
+	USING     = "USING"
+	TAB       = "TAB"
+	SPC       = "SPC"
+	INPUT     = "INPUT"
+	LINE      = "LINE"
+	READ      = "READ"
+	RESTORE   = "RESTORE"
+	RANDOMIZE = "RANDOMIZE"
+	OPEN      = "OPEN"
+	AS        = "AS"
+	OUTPUT    = "OUTPUT"
+	APPEND    = "APPEND"
+	CLOSE     = "CLOSE"
+	WRITE     = "WRITE"
+	KILL      = "KILL"
+	NAME      = "NAME"
+	RANDOM    = "RANDOM"
+	FIELD     = "FIELD"
+	GET       = "GET"
+	PUT       = "PUT"
+	LSET      = "LSET"
+	RSET      = "RSET"
 )
 
+// Position describes where a token starts in the source.
+type Position struct {
+	LineNo int // the last BASIC line number, also on unnumbered lines (0 before any)
+	Line   int // physical line, starting at 1
+	Column int // column in bytes, starting at 1
+	Offset int // byte offset, starting at 0
+}
+
+// IsValid reports whether the position has been set by the lexer.
+func (pos Position) IsValid() bool { return pos.Line > 0 }
+
+func (pos Position) String() string {
+	if !pos.IsValid() {
+		return "-"
+	}
+	return strconv.Itoa(pos.Line) + ":" + strconv.Itoa(pos.Column)
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
 }
 
 var keywords = map[string]TokenType{
-	"LEN":    LEN,
-	"ASC":    ASC,
-	"CHR$":   CHR_D,
-	"DIM":    DIM,
-	"IF":     IF,
-	"THEN":   THEN,
-	"ELSE":   ELSE,
-	"ON":     ON,
-	"GOTO":   GOTO,
-	"GOSUB":  GOSUB,
-	"RETURN": RETURN,
-	"FOR":    FOR,
-	"TO":     TO,
-	"STEP":   STEP,
-	"NEXT":   NEXT,
-	"DATA":   DATA,
-	"REM":    REM,
-	"AND":    AND,
-	"OR":     OR,
+	"LEN":       LEN,
+	"ASC":       ASC,
+	"CHR$":      CHR_D,
+	"DIM":       DIM,
+	"DEF":       DEF,
+	"IF":        IF,
+	"THEN":      THEN,
+	"ELSE":      ELSE,
+	"ELSEIF":    ELSEIF,
+	"END":       END,
+	"STOP":      STOP,
+	"CLEAR":     CLEAR,
+	"SWAP":      SWAP,
+	"ERASE":     ERASE,
+	"ON":        ON,
+	"GOTO":      GOTO,
+	"GOSUB":     GOSUB,
+	"RETURN":    RETURN,
+	"ERROR":     ERROR,
+	"RESUME":    RESUME,
+	"FOR":       FOR,
+	"TO":        TO,
+	"STEP":      STEP,
+	"NEXT":      NEXT,
+	"WHILE":     WHILE,
+	"WEND":      WEND,
+	"DO":        DO,
+	"LOOP":      LOOP,
+	"UNTIL":     UNTIL,
+	"EXIT":      EXIT,
+	"DATA":      DATA,
+	"REM":       REM,
+	"AND":       AND,
+	"OR":        OR,
+	"NOT":       NOT,
+	"XOR":       XOR,
+	"EQV":       EQV,
+	"IMP":       IMP,
+	"MOD":       MOD,
+	"PRINT":     PRINT,
+	"USING":     USING,
+	"TAB":       TAB,
+	"SPC":       SPC,
+	"INPUT":     INPUT,
+	"LINE":      LINE,
+	"READ":      READ,
+	"RESTORE":   RESTORE,
+	"RANDOMIZE": RANDOMIZE,
+	"OPEN":      OPEN,
+	"AS":        AS,
+	"OUTPUT":    OUTPUT,
+	"APPEND":    APPEND,
+	"CLOSE":     CLOSE,
+	"WRITE":     WRITE,
+	"KILL":      KILL,
+	"NAME":      NAME,
+	"RANDOM":    RANDOM,
+	"FIELD":     FIELD,
+	"GET":       GET,
+	"PUT":       PUT,
+	"LSET":      LSET,
+	"RSET":      RSET,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -0,0 +1,92 @@
+package types
+
+import "strings"
+
+// Type is the type of a BASIC value.
+type Type int
+
+const (
+	Invalid Type = iota
+	Integer      // A%
+	Single       // A!, or A without a suffix
+	Double       // A#
+	String       // A$
+)
+
+var typeNames = [...]string{
+	Invalid: "invalid",
+	Integer: "integer",
+	Single:  "single",
+	Double:  "double",
+	String:  "string",
+}
+
+func (t Type) String() string {
+	if t < 0 || int(t) >= len(typeNames) {
+		return "invalid"
+	}
+	return typeNames[t]
+}
+
+func (t Type) IsNumeric() bool {
+	return t == Integer || t == Single || t == Double
+}
+
+// Suffix returns the type declaration character of t.
+func (t Type) Suffix() string {
+	switch t {
+	case Integer:
+		return "%"
+	case Single:
+		return "!"
+	case Double:
+		return "#"
+	case String:
+		return "$"
+	}
+	return ""
+}
+
+// FromName infers the type of a variable from the suffix of its name.
+func FromName(name string) Type {
+	if name == "" {
+		return Invalid
+	}
+
+	switch name[len(name)-1] {
+	case '%':
+		return Integer
+	case '!':
+		return Single
+	case '#':
+		return Double
+	case '$':
+		return String
+	}
+	return Single
+}
+
+// BaseName returns name without its type suffix.
+func BaseName(name string) string {
+	if name != "" && strings.ContainsRune("%!#$", rune(name[len(name)-1])) {
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// Canonical returns the name with the suffix of its type, so that the
+// spellings A and A! of the same variable have the same canonical name.
+func Canonical(name string) string {
+	return BaseName(name) + FromName(name).Suffix()
+}
+
+// Promote returns the type of a numeric operation on a and b.
+func Promote(a, b Type) Type {
+	if !a.IsNumeric() || !b.IsNumeric() {
+		return Invalid
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
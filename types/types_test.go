@@ -0,0 +1,62 @@
+package types
+
+import "testing"
+
+func TestFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Type
+	}{
+		{"A", Single},
+		{"A1", Single},
+		{"A%", Integer},
+		{"A!", Single},
+		{"A#", Double},
+		{"A$", String},
+		{"", Invalid},
+	}
+
+	for _, tt := range tests {
+		if got := FromName(tt.name); got != tt.expected {
+			t.Errorf("FromName(%q) wrong. expected=%s, got=%s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestPromote(t *testing.T) {
+	tests := []struct {
+		a, b     Type
+		expected Type
+	}{
+		{Integer, Integer, Integer},
+		{Integer, Single, Single},
+		{Double, Single, Double},
+		{Integer, Double, Double},
+		{String, Integer, Invalid},
+	}
+
+	for _, tt := range tests {
+		if got := Promote(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Promote(%s, %s) wrong. expected=%s, got=%s", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"A", "A!"},
+		{"A!", "A!"},
+		{"A%", "A%"},
+		{"AB1#", "AB1#"},
+		{"A$", "A$"},
+	}
+
+	for _, tt := range tests {
+		if got := Canonical(tt.name); got != tt.expected {
+			t.Errorf("Canonical(%q) wrong. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+}